@@ -0,0 +1,80 @@
+package access
+
+import (
+	"net/http"
+
+	"systemacontrolya/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CanViewDefect проверяет, видит ли пользователь дефект: админ и руководитель видят всё,
+// менеджеры, инженеры и наблюдатели проекта — все дефекты проекта, остальные — свои.
+func CanViewDefect(db *gorm.DB, userID uint, role string, defect models.Defect) bool {
+	if role == "Админ" || role == "Руководитель" {
+		return true
+	}
+	if defect.AuthorID == userID || (defect.AssigneeID != nil && *defect.AssigneeID == userID) {
+		return true
+	}
+	return HasProjectRole(db, defect.ProjectID, userID, "manager", "engineer", "observer")
+}
+
+// HasProjectRole проверяет, что пользователь состоит в проекте с одной из ролей; без ролей — с любой.
+func HasProjectRole(db *gorm.DB, projectID, userID uint, roles ...string) bool {
+	query := db.Model(&models.ProjectMember{}).Where("project_id = ? AND user_id = ?", projectID, userID)
+	if len(roles) > 0 {
+		query = query.Where("role IN ?", roles)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+func IsProjectManager(db *gorm.DB, projectID, userID uint) bool {
+	return HasProjectRole(db, projectID, userID, "manager")
+}
+
+// MemberProjects возвращает подзапрос ID проектов, в которых состоит пользователь, для условий вида
+// "project_id IN (?)". Без ролей учитывается любое участие.
+func MemberProjects(db *gorm.DB, userID uint, roles ...string) *gorm.DB {
+	query := db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userID)
+	if len(roles) > 0 {
+		query = query.Where("role IN ?", roles)
+	}
+	return query
+}
+
+// ScopeProjects ограничивает запрос по проектам теми, где пользователь состоит. Админ и руководитель видят все проекты.
+func ScopeProjects(db *gorm.DB, query *gorm.DB, column string, userID uint, role string) *gorm.DB {
+	if role == "Админ" || role == "Руководитель" {
+		return query
+	}
+	return query.Where(column+" IN (?)", MemberProjects(db, userID))
+}
+
+// CanViewProject проверяет доступ к данным проекта: админ и руководитель видят все проекты, остальные — те, где состоят.
+func CanViewProject(db *gorm.DB, projectID, userID uint, role string) bool {
+	if role == "Админ" || role == "Руководитель" {
+		return true
+	}
+	return HasProjectRole(db, projectID, userID)
+}
+
+// RequireActiveProject проверяет, что проект не в архиве: данные архивного проекта доступны только
+// для чтения. Иначе отвечает 409 и возвращает false.
+func RequireActiveProject(c *gin.Context, db *gorm.DB, projectID uint) bool {
+	var archived int64
+	if err := db.Model(&models.Project{}).Where("id = ? AND status = ?", projectID, "archived").Count(&archived).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось проверить статус проекта"})
+		return false
+	}
+	if archived > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Проект в архиве, изменения недоступны"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Duration — время прохождения этапа от регистрации дефекта в календарных часах.
+type Duration struct {
+	Stage     string  `json:"stage"`
+	Count     int64   `json:"count"`
+	MeanHours float64 `json:"mean_hours"`
+	P50Hours  float64 `json:"p50_hours"`
+	P90Hours  float64 `json:"p90_hours"`
+}
+
+// AgingBucket — открытые дефекты одного возраста. MaxDays пуст у последней корзины.
+type AgingBucket struct {
+	Label      string           `json:"label"`
+	MinDays    int              `json:"min_days"`
+	MaxDays    *int             `json:"max_days"`
+	Total      int64            `json:"total"`
+	ByPriority map[string]int64 `json:"by_priority"`
+}
+
+// Week — дефекты, созданные и закрытые за неделю, и число открытых на её конец.
+type Week struct {
+	Week    time.Time `json:"week"`
+	Created int64     `json:"created"`
+	Closed  int64     `json:"closed"`
+	Backlog int64     `json:"backlog"`
+}
+
+// stages — этапы, время до которых считается от created_at.
+var stages = []struct {
+	name   string
+	column string
+}{
+	{"assign", "assigned_at"},
+	{"resolve", "resolved_at"},
+	{"close", "closed_at"},
+}
+
+// agingBuckets — границы возрастных корзин открытых дефектов в днях.
+var agingBuckets = []int{0, 7, 30, 90}
+
+// Durations считает среднее, медиану и 90-й процентиль времени до назначения, устранения и закрытия.
+// Этап учитывается, если он завершился в периоде фильтра.
+func Durations(db *gorm.DB, f Filter) ([]Duration, error) {
+	durations := make([]Duration, 0, len(stages))
+	for _, stage := range stages {
+		hours := fmt.Sprintf("EXTRACT(EPOCH FROM d.%s - d.created_at) / 3600", stage.column)
+		duration := Duration{Stage: stage.name}
+		if err := f.defects(db).
+			Select(fmt.Sprintf(`COUNT(*) AS count,
+				COALESCE(ROUND(AVG(%[1]s)::numeric, 2), 0)::float8 AS mean_hours,
+				COALESCE(ROUND((percentile_cont(0.5) WITHIN GROUP (ORDER BY %[1]s))::numeric, 2), 0)::float8 AS p50_hours,
+				COALESCE(ROUND((percentile_cont(0.9) WITHIN GROUP (ORDER BY %[1]s))::numeric, 2), 0)::float8 AS p90_hours`, hours)).
+			Where(fmt.Sprintf("d.%s >= ? AND d.%s < ?", stage.column, stage.column), f.From, f.To).
+			Scan(&duration).Error; err != nil {
+			return nil, err
+		}
+		durations = append(durations, duration)
+	}
+	return durations, nil
+}
+
+// Aging распределяет незакрытые дефекты по возрасту на текущий момент; период фильтра не учитывается.
+func Aging(db *gorm.DB, f Filter, now time.Time) ([]AgingBucket, error) {
+	buckets := make([]AgingBucket, len(agingBuckets))
+	cases := make([]string, 0, len(agingBuckets))
+	var args []any
+	for i, minDays := range agingBuckets {
+		buckets[i] = AgingBucket{MinDays: minDays, ByPriority: map[string]int64{}}
+		if i+1 < len(agingBuckets) {
+			maxDays := agingBuckets[i+1]
+			buckets[i].MaxDays = &maxDays
+			buckets[i].Label = fmt.Sprintf("%d-%d", minDays, maxDays)
+			cases = append(cases, fmt.Sprintf("WHEN d.created_at > ? THEN %d", i))
+			args = append(args, now.AddDate(0, 0, -maxDays))
+		} else {
+			buckets[i].Label = fmt.Sprintf("%d+", minDays)
+		}
+	}
+	bucketExpr := fmt.Sprintf("CASE %s ELSE %d END", strings.Join(cases, " "), len(agingBuckets)-1)
+
+	var rows []struct {
+		Bucket   int
+		Priority string
+		Count    int64
+	}
+	if err := f.defects(db).
+		Select(bucketExpr+" AS bucket, d.priority, COUNT(*) AS count", args...).
+		Where("d.status <> ?", "closed").
+		Group("bucket, d.priority").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		buckets[row.Bucket].ByPriority[row.Priority] = row.Count
+		buckets[row.Bucket].Total += row.Count
+	}
+	return buckets, nil
+}
+
+// Throughput считает по неделям созданные и закрытые дефекты и остаток открытых на конец недели.
+// Остаток восстанавливается по created_at и closed_at: от числа открытых на начало периода
+// прибавляются созданные и вычитаются закрытые, так что число запросов не зависит от длины периода.
+// Недели начинаются с понедельника по UTC.
+func Throughput(db *gorm.DB, f Filter) ([]Week, error) {
+	start := weekStart(f.From)
+
+	created, err := weekly(f.defects(db), "created_at", start, f.To)
+	if err != nil {
+		return nil, err
+	}
+	closed, err := weekly(f.defects(db), "closed_at", start, f.To)
+	if err != nil {
+		return nil, err
+	}
+
+	var backlog int64
+	if err := f.defects(db).
+		Where("d.created_at < ? AND (d.closed_at IS NULL OR d.closed_at >= ?)", start, start).
+		Count(&backlog).Error; err != nil {
+		return nil, err
+	}
+
+	weeks := []Week{}
+	for week := start; week.Before(f.To); week = week.AddDate(0, 0, 7) {
+		key := week.Format("2006-01-02")
+		backlog += created[key] - closed[key]
+		weeks = append(weeks, Week{Week: week, Created: created[key], Closed: closed[key], Backlog: backlog})
+	}
+	return weeks, nil
+}
+
+func weekly(query *gorm.DB, column string, from, to time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Week  time.Time
+		Count int64
+	}
+	if err := query.
+		Select(fmt.Sprintf("date_trunc('week', d.%s AT TIME ZONE 'UTC') AS week, COUNT(*) AS count", column)).
+		Where(fmt.Sprintf("d.%s >= ? AND d.%s < ?", column, column), from, to).
+		Group("week").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Week.Format("2006-01-02")] = row.Count
+	}
+	return counts, nil
+}
+
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"fmt"
+
+	"systemacontrolya/internal/costs"
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// costGroups — разрезы свода затрат: выражение ключа, название группы и нужное соединение.
+var costGroups = map[string]struct {
+	key  string
+	name string
+	join string
+}{
+	"project": {
+		key:  "c.project_id::text",
+		name: "p.name",
+		join: "JOIN projects p ON p.id = c.project_id",
+	},
+	"location": {
+		key:  "COALESCE(l.id::text, '')",
+		name: "COALESCE(l.name, 'Без места')",
+		join: "LEFT JOIN locations l ON l.id = c.location_id",
+	},
+	"cause": {
+		key:  "COALESCE(l.id::text, '')",
+		name: "COALESCE(l.name, 'Причина не указана')",
+		join: "LEFT JOIN (defect_labels dl JOIN labels l ON l.id = dl.label_id AND l.kind = 'cause') ON dl.defect_id = c.id",
+	},
+	"organization": {
+		key:  "COALESCE(o.id::text, '')",
+		name: "COALESCE(o.name, 'Организация не назначена')",
+		join: "LEFT JOIN organizations o ON o.id = c.organization_id",
+	},
+	"party": {
+		key:  "COALESCE(c.responsible_party, '')",
+		name: "COALESCE(c.responsible_party, '')",
+	},
+}
+
+// Costs сводит затраты на дефекты, выявленные в периоде фильтра, по проектам, местам, причинам
+// (метки вида cause), организациям или ответственной стороне. Дефект с несколькими причинами учитывается в каждой,
+// поэтому сумма по причинам может превышать общие затраты.
+func Costs(db *gorm.DB, f Filter, by string) ([]models.CostReportRow, error) {
+	group, ok := costGroups[by]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный разрез %q", by)
+	}
+
+	defects := f.defects(db).
+		Select("d.id, d.project_id, d.location_id, d.organization_id, d.responsible_party, "+costs.Columns).
+		Where("d.created_at >= ? AND d.created_at < ?", f.From, f.To)
+	query := db.Table("(?) c", defects)
+	if group.join != "" {
+		query = query.Joins(group.join)
+	}
+
+	rows := []models.CostReportRow{}
+	if err := query.
+		Select(group.key + " AS key, " + group.name + ` AS name, COUNT(DISTINCT c.id) AS defects,
+			SUM(c.estimated_labour)::float8 AS estimated_labour, SUM(c.actual_labour)::float8 AS actual_labour,
+			SUM(c.materials)::float8 AS materials, SUM(c.actual_labour + c.materials)::float8 AS total`).
+		Group("1, 2").
+		Order("total DESC, name").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if by == "party" {
+		for i := range rows {
+			rows[i].Name = models.ResponsiblePartyNames[rows[i].Key]
+			if rows[i].Name == "" {
+				rows[i].Name = "Сторона не указана"
+			}
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,100 @@
+package analytics
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// defaultPeriod — период по умолчанию, если from не задан.
+const defaultPeriod = 12 * 7 * 24 * time.Hour
+
+// Filter — общие условия отбора дефектов для всех показателей. From и To ограничивают события
+// (создание, назначение, закрытие), а не только дату создания дефекта.
+type Filter struct {
+	ProjectID  *uint  `json:"project_id"`
+	Priority   string `json:"priority,omitempty"`
+	AssigneeID *uint  `json:"assignee_id"`
+	// OrganizationID — организация, которой поручены дефекты.
+	OrganizationID *uint     `json:"organization_id"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+
+	projects *gorm.DB
+}
+
+// ParseFilter читает project_id, priority, assignee_id, organization_id, from и to (ГГГГ-ММ-ДД) из строки запроса.
+// По умолчанию берутся последние 12 недель по текущий день включительно.
+func ParseFilter(query url.Values, now time.Time) (Filter, error) {
+	var filter Filter
+	var err error
+
+	if filter.ProjectID, err = utils.ParseOptionalID(query.Get("project_id")); err != nil {
+		return filter, fmt.Errorf("неверный project_id")
+	}
+	if filter.AssigneeID, err = utils.ParseOptionalID(query.Get("assignee_id")); err != nil {
+		return filter, fmt.Errorf("неверный assignee_id")
+	}
+	if filter.OrganizationID, err = utils.ParseOptionalID(query.Get("organization_id")); err != nil {
+		return filter, fmt.Errorf("неверный organization_id")
+	}
+	if priority := query.Get("priority"); priority != "" {
+		if _, ok := models.PriorityNames[priority]; !ok {
+			return filter, fmt.Errorf("неизвестный приоритет %q", priority)
+		}
+		filter.Priority = priority
+	}
+
+	filter.To = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	if to := query.Get("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return filter, fmt.Errorf("неверный формат даты 'to'")
+		}
+		filter.To = date.AddDate(0, 0, 1)
+	}
+	filter.From = filter.To.Add(-defaultPeriod)
+	if from := query.Get("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return filter, fmt.Errorf("неверный формат даты 'from'")
+		}
+		filter.From = date
+	}
+	if !filter.From.Before(filter.To) {
+		return filter, fmt.Errorf("дата 'from' должна быть раньше 'to'")
+	}
+	return filter, nil
+}
+
+// Within ограничивает отбор проектами из подзапроса ID, например access.MemberProjects.
+func (f Filter) Within(projects *gorm.DB) Filter {
+	f.projects = projects
+	return f
+}
+
+// defects возвращает запрос по таблице defects с псевдонимом d и условиями фильтра, кроме периода.
+func (f Filter) defects(db *gorm.DB) *gorm.DB {
+	query := db.Table("defects d").Where("d.deleted_at IS NULL")
+	if f.projects != nil {
+		query = query.Where("d.project_id IN (?)", f.projects)
+	}
+	if f.ProjectID != nil {
+		query = query.Where("d.project_id = ?", *f.ProjectID)
+	}
+	if f.Priority != "" {
+		query = query.Where("d.priority = ?", f.Priority)
+	}
+	if f.AssigneeID != nil {
+		query = query.Where("d.assignee_id = ?", *f.AssigneeID)
+	}
+	if f.OrganizationID != nil {
+		query = query.Where("d.organization_id = ?", *f.OrganizationID)
+	}
+	return query
+}
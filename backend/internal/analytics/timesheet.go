@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"fmt"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TimeReport суммирует записи о работе по периодам, проектам и исполнителям. Период фильтра
+// ограничивает дату работы, assignee_id — того, кто записал работу, а не текущего исполнителя дефекта.
+// Периоды группировки: day, week (с понедельника) и month.
+func TimeReport(db *gorm.DB, f Filter, period string) ([]models.TimeReportRow, error) {
+	if _, ok := models.TimePeriodNames[period]; !ok {
+		return nil, fmt.Errorf("неизвестный период группировки %q", period)
+	}
+	userID := f.AssigneeID
+	f.AssigneeID = nil
+
+	query := f.defects(db).
+		Joins("JOIN work_logs w ON w.defect_id = d.id").
+		Joins("JOIN projects p ON p.id = d.project_id AND p.deleted_at IS NULL").
+		Where("w.work_date >= ? AND w.work_date < ?", f.From, f.To)
+	if userID != nil {
+		query = query.Where("w.user_id = ?", *userID)
+	}
+
+	var rows []models.TimeReportRow
+	if err := query.
+		Select(`date_trunc(?, w.work_date)::date AS period, d.project_id, p.name AS project_name, w.user_id,
+			SUM(w.hours)::float8 AS hours, COUNT(*) AS entries, COUNT(DISTINCT w.defect_id) AS defects`, period).
+		Group("1, d.project_id, p.name, w.user_id").
+		Order("period, p.name, w.user_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []models.TimeReportRow{}, nil
+	}
+
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.UserID)
+	}
+	var users []models.User
+	if err := db.Unscoped().Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	names := map[uint]string{}
+	for _, user := range users {
+		names[user.ID] = user.FullName()
+	}
+	for i := range rows {
+		rows[i].UserName = names[rows[i].UserID]
+	}
+	return rows, nil
+}
@@ -0,0 +1,240 @@
+package analytics
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Workload — нагрузка и результативность одного исполнителя или менеджера.
+type Workload struct {
+	ID             uint             `json:"id"`
+	Name           string           `json:"name"`
+	Open           int64            `json:"open"`
+	OpenByPriority map[string]int64 `json:"open_by_priority"`
+	Overdue        int64            `json:"overdue"`
+
+	AvgAssignHours     float64 `json:"avg_assign_hours"`
+	AvgResolutionHours float64 `json:"avg_resolution_hours"`
+
+	Reviews       int64   `json:"reviews"`
+	Rejected      int64   `json:"rejected"`
+	RejectionRate float64 `json:"rejection_rate"`
+
+	Trend []TrendPoint `json:"trend"`
+}
+
+// TrendPoint — число дефектов, доведённых до результата за неделю: устранённых для исполнителя,
+// закрытых для менеджера.
+type TrendPoint struct {
+	Week  time.Time `json:"week"`
+	Count int64     `json:"count"`
+}
+
+// grouping описывает, по кому собирается нагрузка.
+type grouping struct {
+	key       string // выражение ответственного по дефекту
+	join      string // соединение, нужное для key
+	reviewKey string // выражение ответственного по рецензии отчёта
+	done      string // колонка, по которой строится тренд
+	names     func(db *gorm.DB, ids []uint) (map[uint]string, error)
+}
+
+var (
+	byAssignee = grouping{
+		key:       "d.assignee_id",
+		reviewKey: "r.user_id",
+		done:      "resolved_at",
+		names:     userNames,
+	}
+	byManager = grouping{
+		key:       "p.manager_id",
+		join:      "JOIN projects p ON p.id = d.project_id",
+		reviewKey: "p.manager_id",
+		done:      "closed_at",
+		names:     userNames,
+	}
+	byOrganization = grouping{
+		key:       "d.organization_id",
+		reviewKey: "d.organization_id",
+		done:      "resolved_at",
+		names:     organizationNames,
+	}
+)
+
+// AssigneeWorkload собирает нагрузку по исполнителям: открытые дефекты по приоритетам, просрочку,
+// среднее время устранения от назначения, долю отклонённых отчётов исполнителя и недельный тренд.
+func AssigneeWorkload(db *gorm.DB, f Filter, now time.Time) ([]Workload, error) {
+	return workload(db, f, byAssignee, now)
+}
+
+// OrganizationWorkload собирает показатели исполнителя по организациям, которым поручены дефекты:
+// открытые и просроченные дефекты, время устранения и долю отклонённых отчётов сотрудников.
+func OrganizationWorkload(db *gorm.DB, f Filter, now time.Time) ([]Workload, error) {
+	return workload(db, f, byOrganization, now)
+}
+
+// ManagerWorkload собирает те же показатели по ведущим менеджерам проектов; дополнительно считается
+// время до назначения исполнителя, а тренд строится по закрытым дефектам.
+func ManagerWorkload(db *gorm.DB, f Filter, now time.Time) ([]Workload, error) {
+	return workload(db, f, byManager, now)
+}
+
+func workload(db *gorm.DB, f Filter, g grouping, now time.Time) ([]Workload, error) {
+	rows := map[uint]*Workload{}
+	get := func(id uint) *Workload {
+		if rows[id] == nil {
+			rows[id] = &Workload{ID: id, OpenByPriority: map[string]int64{}, Trend: []TrendPoint{}}
+		}
+		return rows[id]
+	}
+	base := func() *gorm.DB {
+		query := f.defects(db).Where(g.key + " IS NOT NULL")
+		if g.join != "" {
+			query = query.Joins(g.join)
+		}
+		return query
+	}
+
+	var open []struct {
+		ID       uint
+		Priority string
+		Count    int64
+		Overdue  int64
+	}
+	if err := base().
+		Select(g.key+" AS id, d.priority, COUNT(*) AS count, COUNT(*) FILTER (WHERE d.due_date < ?) AS overdue", now).
+		Where("d.status NOT IN ?", []string{"resolved", "closed"}).
+		Group(g.key + ", d.priority").
+		Scan(&open).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range open {
+		w := get(row.ID)
+		w.OpenByPriority[row.Priority] = row.Count
+		w.Open += row.Count
+		w.Overdue += row.Overdue
+	}
+
+	var durations []struct {
+		ID                 uint
+		AvgAssignHours     float64
+		AvgResolutionHours float64
+	}
+	if err := base().
+		Select(g.key+` AS id,
+			COALESCE(ROUND((AVG(EXTRACT(EPOCH FROM d.assigned_at - d.created_at) / 3600) FILTER (WHERE d.assigned_at >= ? AND d.assigned_at < ?))::numeric, 2), 0)::float8 AS avg_assign_hours,
+			COALESCE(ROUND((AVG(EXTRACT(EPOCH FROM d.resolved_at - COALESCE(d.assigned_at, d.created_at)) / 3600) FILTER (WHERE d.resolved_at >= ? AND d.resolved_at < ?))::numeric, 2), 0)::float8 AS avg_resolution_hours`,
+			f.From, f.To, f.From, f.To).
+		Where("(d.assigned_at >= ? AND d.assigned_at < ?) OR (d.resolved_at >= ? AND d.resolved_at < ?)", f.From, f.To, f.From, f.To).
+		Group(g.key).
+		Scan(&durations).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range durations {
+		w := get(row.ID)
+		w.AvgAssignHours = row.AvgAssignHours
+		w.AvgResolutionHours = row.AvgResolutionHours
+	}
+
+	var reviews []struct {
+		ID       uint
+		Reviews  int64
+		Rejected int64
+	}
+	if err := base().
+		Select(g.reviewKey+" AS id, COUNT(*) AS reviews, COUNT(*) FILTER (WHERE rr.decision = 'reject') AS rejected").
+		Joins("JOIN reports r ON r.defect_id = d.id AND r.deleted_at IS NULL").
+		Joins("JOIN report_reviews rr ON rr.report_id = r.id").
+		Where("rr.created_at >= ? AND rr.created_at < ?", f.From, f.To).
+		Group(g.reviewKey).
+		Scan(&reviews).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range reviews {
+		w := get(row.ID)
+		w.Reviews = row.Reviews
+		w.Rejected = row.Rejected
+		if row.Reviews > 0 {
+			w.RejectionRate = float64(int(float64(row.Rejected)/float64(row.Reviews)*10000)) / 100
+		}
+	}
+
+	var trend []struct {
+		ID    uint
+		Week  time.Time
+		Count int64
+	}
+	if err := base().
+		Select(g.key+" AS id, date_trunc('week', d."+g.done+" AT TIME ZONE 'UTC') AS week, COUNT(*) AS count").
+		Where("d."+g.done+" >= ? AND d."+g.done+" < ?", weekStart(f.From), f.To).
+		Group(g.key + ", week").
+		Scan(&trend).Error; err != nil {
+		return nil, err
+	}
+	counts := map[uint]map[string]int64{}
+	for _, row := range trend {
+		get(row.ID)
+		if counts[row.ID] == nil {
+			counts[row.ID] = map[string]int64{}
+		}
+		counts[row.ID][row.Week.Format("2006-01-02")] = row.Count
+	}
+	for id, w := range rows {
+		for week := weekStart(f.From); week.Before(f.To); week = week.AddDate(0, 0, 7) {
+			w.Trend = append(w.Trend, TrendPoint{Week: week, Count: counts[id][week.Format("2006-01-02")]})
+		}
+	}
+
+	if len(rows) == 0 {
+		return []Workload{}, nil
+	}
+	ids := make([]uint, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	names, err := g.names(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, name := range names {
+		rows[id].Name = name
+	}
+
+	result := make([]Workload, 0, len(rows))
+	for _, w := range rows {
+		result = append(result, *w)
+	}
+	slices.SortFunc(result, func(a, b Workload) int {
+		return cmp.Or(cmp.Compare(b.Open, a.Open), cmp.Compare(a.Name, b.Name))
+	})
+	return result, nil
+}
+
+func userNames(db *gorm.DB, ids []uint) (map[uint]string, error) {
+	var users []models.User
+	if err := db.Unscoped().Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.FullName()
+	}
+	return names, nil
+}
+
+func organizationNames(db *gorm.DB, ids []uint) (map[uint]string, error) {
+	var organizations []models.Organization
+	if err := db.Where("id IN ?", ids).Find(&organizations).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(organizations))
+	for _, organization := range organizations {
+		names[organization.ID] = organization.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,114 @@
+package assignments
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/delegations"
+	"systemacontrolya/internal/mailer"
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Check проверяет, что пользователю можно поручить дефект проекта.
+func Check(db *gorm.DB, projectID, userID uint) (models.User, error) {
+	var assignee models.User
+	if err := db.First(&assignee, userID).Error; err != nil {
+		return assignee, fmt.Errorf("исполнитель не найден")
+	}
+	if assignee.RoleID != 5 {
+		return assignee, fmt.Errorf("выбранный пользователь не является исполнителем")
+	}
+	if assignee.Status != "active" {
+		return assignee, fmt.Errorf("исполнитель деактивирован")
+	}
+	if !access.HasProjectRole(db, projectID, assignee.ID, "assignee") {
+		return assignee, fmt.Errorf("исполнитель не входит в команду проекта")
+	}
+	return assignee, nil
+}
+
+// Pick выбирает фактического исполнителя: если выбранный отсутствует, дефект получает его заместитель.
+// Второе значение — отсутствующий исполнитель, за которого работает заместитель.
+func Pick(db *gorm.DB, projectID, userID uint, now time.Time) (models.User, *uint, error) {
+	assignee, err := Check(db, projectID, userID)
+	if err != nil {
+		return assignee, nil, err
+	}
+	delegateID, err := delegations.Resolve(db, userID, now)
+	if err != nil {
+		return assignee, nil, err
+	}
+	if delegateID == userID {
+		return assignee, nil, nil
+	}
+	delegate, err := Check(db, projectID, delegateID)
+	if err != nil {
+		return delegate, nil, fmt.Errorf("исполнитель отсутствует, его заместитель не подходит: %w", err)
+	}
+	return delegate, &assignee.ID, nil
+}
+
+// Record добавляет запись в историю назначений дефекта. previous — прежний исполнитель и организация.
+func Record(db *gorm.DB, kind string, defect models.Defect, previousUserID, previousOrganizationID, delegatedFromID *uint, actorID uint, reason string) error {
+	entry := models.DefectAssignment{
+		Kind:               kind,
+		Reason:             reason,
+		DefectID:           defect.ID,
+		FromUserID:         previousUserID,
+		ToUserID:           defect.AssigneeID,
+		DelegatedFromID:    delegatedFromID,
+		FromOrganizationID: previousOrganizationID,
+		ToOrganizationID:   defect.OrganizationID,
+		ActorID:            &actorID,
+	}
+	return db.Omit("Defect", "FromUser", "ToUser", "DelegatedFrom", "FromOrganization", "ToOrganization", "Actor").Create(&entry).Error
+}
+
+// History возвращает историю назначений дефекта по порядку.
+func History(db *gorm.DB, defectID uint) ([]models.DefectAssignment, error) {
+	var list []models.DefectAssignment
+	err := db.Preload("FromUser", models.WithDeleted).
+		Preload("ToUser", models.WithDeleted).
+		Preload("DelegatedFrom", models.WithDeleted).
+		Preload("Actor", models.WithDeleted).
+		Preload("FromOrganization").
+		Preload("ToOrganization").
+		Where("defect_id = ?", defectID).
+		Order("created_at, id").
+		Find(&list).Error
+	return list, err
+}
+
+// NotifyReassigned сообщает прежнему и новому исполнителю о переназначении дефекта.
+// Ошибки отправки только пишутся в лог: переназначение уже сохранено.
+func NotifyReassigned(sender mailer.Sender, defect models.Defect, previous, next models.User, reason string) {
+	messages := []mailer.Message{
+		{
+			To:      []string{previous.Email},
+			Subject: fmt.Sprintf("Дефект #%d передан другому исполнителю", defect.ID),
+			Body: fmt.Sprintf(
+				"Здравствуйте, %s!\n\n"+
+					"Дефект #%d «%s» передан исполнителю %s %s.\n"+
+					"Причина: %s\n",
+				previous.FirstName, defect.ID, defect.Title, next.LastName, next.FirstName, reason),
+		},
+		{
+			To:      []string{next.Email},
+			Subject: fmt.Sprintf("Вам назначен дефект #%d", defect.ID),
+			Body: fmt.Sprintf(
+				"Здравствуйте, %s!\n\n"+
+					"Вам передан дефект #%d «%s», ранее назначенный исполнителю %s %s.\n"+
+					"Причина: %s\n",
+				next.FirstName, defect.ID, defect.Title, previous.LastName, previous.FirstName, reason),
+		},
+	}
+	for _, message := range messages {
+		if err := sender.Send(message); err != nil {
+			log.Printf("reassignment notice: defect %d: %v", defect.ID, err)
+		}
+	}
+}
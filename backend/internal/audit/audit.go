@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"reflect"
+	"slices"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Record пишет запись в audit_logs. old и new — снимки записи до и после изменения;
+// список изменённых полей вычисляется по ним. actorID равен 0, если изменение сделала система.
+func Record(db *gorm.DB, table string, recordID uint, action string, actorID uint, old, new map[string]any, comment string) error {
+	entry := models.AuditLog{
+		Table:         table,
+		RecordID:      recordID,
+		Action:        action,
+		OldData:       old,
+		NewData:       new,
+		ChangedFields: Changed(old, new),
+		Comment:       comment,
+	}
+	if actorID != 0 {
+		entry.UserID = &actorID
+	}
+	return db.Create(&entry).Error
+}
+
+// Changed возвращает отсортированный список ключей, значения которых различаются.
+func Changed(old, new map[string]any) []string {
+	changed := []string{}
+	for key, value := range new {
+		if previous, ok := old[key]; !ok || !reflect.DeepEqual(previous, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// History возвращает записи аудита по одной записи таблицы, начиная с последних.
+func History(db *gorm.DB, table string, recordID uint) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	err := db.Preload("User", models.WithDeleted).
+		Where("table_name = ? AND record_id = ?", table, recordID).
+		Order("timestamp DESC, id DESC").
+		Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,182 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// maxScanDays ограничивает перебор дней, чтобы некорректный календарь не зациклил расчёт.
+const maxScanDays = 3660
+
+type Calendar struct {
+	location *time.Location
+	start    time.Duration
+	end      time.Duration
+	weekends map[time.Weekday]bool
+	dated    map[string]bool
+	yearly   map[string]bool
+}
+
+// Default — пятидневка с 9:00 до 18:00 по Москве, используется если в базе нет календаря.
+func Default() *Calendar {
+	cal, _ := New(models.WorkCalendar{
+		Timezone:  "Europe/Moscow",
+		WorkStart: "09:00",
+		WorkEnd:   "18:00",
+		Weekends:  []int{int(time.Saturday), int(time.Sunday)},
+	})
+	return cal
+}
+
+func New(wc models.WorkCalendar) (*Calendar, error) {
+	location, err := time.LoadLocation(wc.Timezone)
+	if err != nil {
+		location = time.FixedZone("MSK", 3*60*60)
+	}
+
+	start, err := ParseClock(wc.WorkStart)
+	if err != nil {
+		return nil, err
+	}
+	end, err := ParseClock(wc.WorkEnd)
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, fmt.Errorf("конец рабочего дня должен быть позже начала")
+	}
+
+	cal := &Calendar{
+		location: location,
+		start:    start,
+		end:      end,
+		weekends: make(map[time.Weekday]bool, len(wc.Weekends)),
+		dated:    make(map[string]bool, len(wc.Holidays)),
+		yearly:   make(map[string]bool),
+	}
+	for _, day := range wc.Weekends {
+		cal.weekends[time.Weekday(day)] = true
+	}
+	for _, holiday := range wc.Holidays {
+		if holiday.Recurring {
+			cal.yearly[holiday.Date.Format("01-02")] = holiday.Working
+		} else {
+			cal.dated[holiday.Date.Format("2006-01-02")] = holiday.Working
+		}
+	}
+	return cal, nil
+}
+
+func ParseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("неверный формат времени %q, ожидается ЧЧ:ММ", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ForProject возвращает календарь проекта, а если он не переопределён — общий календарь.
+// Праздники общего календаря действуют и в проекте; свои дни проекта переопределяют их.
+func ForProject(db *gorm.DB, projectID uint) *Calendar {
+	var global, wc models.WorkCalendar
+	globalErr := db.Preload("Holidays").Where("project_id IS NULL").Order("id").First(&global).Error
+	if err := db.Preload("Holidays").Where("project_id = ?", projectID).First(&wc).Error; err == nil {
+		if globalErr == nil {
+			wc.Holidays = append(global.Holidays, wc.Holidays...)
+		}
+	} else if globalErr == nil {
+		wc = global
+	} else {
+		return Default()
+	}
+
+	cal, err := New(wc)
+	if err != nil {
+		return Default()
+	}
+	return cal
+}
+
+func (cal *Calendar) IsWorkingDay(t time.Time) bool {
+	t = t.In(cal.location)
+	if working, ok := cal.dated[t.Format("2006-01-02")]; ok {
+		return working
+	}
+	if working, ok := cal.yearly[t.Format("01-02")]; ok {
+		return working
+	}
+	return !cal.weekends[t.Weekday()]
+}
+
+func (cal *Calendar) dayWindow(t time.Time) (time.Time, time.Time) {
+	t = t.In(cal.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, cal.location)
+	return midnight.Add(cal.start), midnight.Add(cal.end)
+}
+
+func nextMidnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+// AddWorkingHours прибавляет к моменту from указанное количество рабочих часов.
+func (cal *Calendar) AddWorkingHours(from time.Time, hours int) time.Time {
+	remaining := time.Duration(hours) * time.Hour
+	current := from.In(cal.location)
+
+	for i := 0; i < maxScanDays; i++ {
+		if cal.IsWorkingDay(current) {
+			dayStart, dayEnd := cal.dayWindow(current)
+			if current.Before(dayStart) {
+				current = dayStart
+			}
+			if current.Before(dayEnd) {
+				available := dayEnd.Sub(current)
+				if remaining <= available {
+					return current.Add(remaining)
+				}
+				remaining -= available
+			}
+		}
+		current = nextMidnight(current)
+	}
+
+	return from.Add(time.Duration(hours) * time.Hour)
+}
+
+// WorkingDuration возвращает рабочее время между двумя моментами.
+func (cal *Calendar) WorkingDuration(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+
+	var total time.Duration
+	current := from.In(cal.location)
+	for i := 0; i < maxScanDays && current.Before(to); i++ {
+		if cal.IsWorkingDay(current) {
+			dayStart, dayEnd := cal.dayWindow(current)
+			if current.After(dayStart) {
+				dayStart = current
+			}
+			if to.Before(dayEnd) {
+				dayEnd = to
+			}
+			if dayEnd.After(dayStart) {
+				total += dayEnd.Sub(dayStart)
+			}
+		}
+		current = nextMidnight(current)
+	}
+	return total
+}
+
+// IsOverdue сообщает, истёк ли срок, с учётом того что вне рабочего времени срок не наступает.
+func (cal *Calendar) IsOverdue(due *time.Time, now time.Time) bool {
+	if due == nil || !now.After(*due) {
+		return false
+	}
+	return cal.WorkingDuration(*due, now) > 0
+}
@@ -0,0 +1,85 @@
+package costs
+
+import (
+	"fmt"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Columns — стоимость дефекта с псевдонимом d: оценка работ, факт работ и материалы. Факт работ,
+// не заданный менеджером, складывается из стоимости работ в неотклонённых отчётах.
+const Columns = `COALESCE(d.estimated_labour_cost, 0) AS estimated_labour,
+	COALESCE(d.actual_labour_cost, (SELECT SUM(r.labour_cost) FROM reports r
+		WHERE r.defect_id = d.id AND r.deleted_at IS NULL AND r.status <> 'reject'), 0) AS actual_labour,
+	COALESCE((SELECT SUM(m.quantity * m.unit_price) FROM defect_materials m WHERE m.defect_id = d.id), 0) AS materials`
+
+// DefectCosts — затраты на устранение одного дефекта. Total — факт работ и материалы.
+type DefectCosts struct {
+	EstimatedLabour float64 `json:"estimated_labour"`
+	ActualLabour    float64 `json:"actual_labour"`
+	Materials       float64 `json:"materials"`
+	Total           float64 `json:"total"`
+}
+
+// ForDefects считает затраты по списку дефектов одним запросом.
+func ForDefects(db *gorm.DB, ids []uint) (map[uint]DefectCosts, error) {
+	result := map[uint]DefectCosts{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		ID uint
+		DefectCosts
+	}
+	if err := db.Table("defects d").
+		Select("d.id, "+Columns).
+		Where("d.id IN ?", ids).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		row.Total = row.ActualLabour + row.Materials
+		result[row.ID] = row.DefectCosts
+	}
+	return result, nil
+}
+
+// Sum складывает затраты по дефектам из подзапроса ID.
+func Sum(db *gorm.DB, defectIDs *gorm.DB) (DefectCosts, error) {
+	var total DefectCosts
+	err := db.Table("(?) c", db.Table("defects d").Select(Columns).Where("d.id IN (?)", defectIDs)).
+		Select(`COALESCE(SUM(c.estimated_labour), 0) AS estimated_labour,
+			COALESCE(SUM(c.actual_labour), 0) AS actual_labour,
+			COALESCE(SUM(c.materials), 0) AS materials`).
+		Scan(&total).Error
+	total.Total = total.ActualLabour + total.Materials
+	return total, err
+}
+
+// AddMaterial списывает материал из справочника проекта на дефект по текущей цене.
+// Если указан отчёт, он должен относиться к этому дефекту.
+func AddMaterial(db *gorm.DB, defect models.Defect, input models.DefectMaterialInput, actorID uint) (models.DefectMaterial, error) {
+	usage := models.DefectMaterial{DefectID: defect.ID, MaterialID: input.MaterialID, Quantity: input.Quantity, ReportID: input.ReportID}
+	if actorID != 0 {
+		usage.CreatedByID = &actorID
+	}
+
+	if err := db.Where("project_id = ?", defect.ProjectID).First(&usage.Material, input.MaterialID).Error; err != nil {
+		return usage, fmt.Errorf("материал не найден в справочнике проекта")
+	}
+	if input.ReportID != nil {
+		var count int64
+		if err := db.Model(&models.Report{}).Where("id = ? AND defect_id = ?", *input.ReportID, defect.ID).Count(&count).Error; err != nil {
+			return usage, err
+		}
+		if count == 0 {
+			return usage, fmt.Errorf("отчёт не относится к этому дефекту")
+		}
+	}
+	usage.UnitPrice = usage.Material.UnitPrice
+
+	err := db.Omit("Material").Create(&usage).Error
+	return usage, err
+}
@@ -0,0 +1,200 @@
+package customfields
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// FilterPrefix — префикс параметров запроса для фильтрации по пользовательским полям: ?cf.section=2&cf.area.gte=10
+const FilterPrefix = "cf."
+
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+func ProjectFields(db *gorm.DB, projectID uint) ([]models.CustomField, error) {
+	var fields []models.CustomField
+	err := db.Where("project_id = ?", projectID).Order("position, id").Find(&fields).Error
+	return fields, err
+}
+
+// ParseForm разбирает значения полей, переданные в multipart-форме строкой JSON.
+func ParseForm(raw string) (map[string]any, error) {
+	values := map[string]any{}
+	if strings.TrimSpace(raw) == "" {
+		return values, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, fmt.Errorf("пользовательские поля должны быть объектом JSON")
+	}
+	return values, nil
+}
+
+// Validate проверяет значения по определениям полей проекта и приводит их к каноническому виду.
+// current — уже сохранённые значения дефекта, с которыми объединяются новые; nil в значении удаляет поле.
+func Validate(db *gorm.DB, projectID uint, current, values map[string]any) (map[string]any, error) {
+	fields, err := ProjectFields(db, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]models.CustomField, len(fields))
+	for _, field := range fields {
+		byKey[field.Key] = field
+	}
+
+	result := make(map[string]any, len(current)+len(values))
+	for key, value := range current {
+		if _, ok := byKey[key]; ok {
+			result[key] = value
+		}
+	}
+
+	for key, value := range values {
+		field, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("поле %q не определено в проекте", key)
+		}
+		if value == nil || value == "" {
+			delete(result, key)
+			continue
+		}
+
+		normalized, err := normalize(db, field, value)
+		if err != nil {
+			return nil, fmt.Errorf("поле «%s»: %v", field.Name, err)
+		}
+		result[key] = normalized
+	}
+
+	for _, field := range fields {
+		if _, ok := result[field.Key]; field.Required && !ok {
+			return nil, fmt.Errorf("поле «%s» обязательно", field.Name)
+		}
+	}
+
+	return result, nil
+}
+
+func normalize(db *gorm.DB, field models.CustomField, value any) (any, error) {
+	switch field.Type {
+	case "text":
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("ожидается строка")
+		}
+		if len([]rune(text)) > 500 {
+			return nil, fmt.Errorf("не более 500 символов")
+		}
+		return text, nil
+
+	case "number":
+		return toNumber(value)
+
+	case "date":
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("ожидается дата в формате ГГГГ-ММ-ДД")
+		}
+		date, err := time.Parse("2006-01-02", text)
+		if err != nil {
+			return nil, fmt.Errorf("ожидается дата в формате ГГГГ-ММ-ДД")
+		}
+		return date.Format("2006-01-02"), nil
+
+	case "enum":
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("ожидается одно из значений списка")
+		}
+		for _, option := range field.Options {
+			if option == text {
+				return text, nil
+			}
+		}
+		return nil, fmt.Errorf("значение %q отсутствует в списке", text)
+
+	case "user":
+		number, err := toNumber(value)
+		if err != nil || number != float64(uint(number)) {
+			return nil, fmt.Errorf("ожидается ID пользователя")
+		}
+		var count int64
+		if err := db.Model(&models.User{}).Where("id = ?", uint(number)).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("пользователь не найден")
+		}
+		return uint(number), nil
+	}
+
+	return nil, fmt.Errorf("неизвестный тип поля %q", field.Type)
+}
+
+func toNumber(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		number, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+		if err != nil {
+			return 0, fmt.Errorf("ожидается число")
+		}
+		return number, nil
+	}
+	return 0, fmt.Errorf("ожидается число")
+}
+
+// FilterDefects применяет фильтры вида cf.<key>=<value>, cf.<key>.gte=<value> и cf.<key>.lte=<value>.
+func FilterDefects(query *gorm.DB, params map[string][]string) (*gorm.DB, error) {
+	for param, values := range params {
+		if !strings.HasPrefix(param, FilterPrefix) || len(values) == 0 {
+			continue
+		}
+
+		key := strings.TrimPrefix(param, FilterPrefix)
+		op := "="
+		if base, ok := strings.CutSuffix(key, ".gte"); ok {
+			key, op = base, ">="
+		} else if base, ok := strings.CutSuffix(key, ".lte"); ok {
+			key, op = base, "<="
+		}
+		if !ValidKey(key) {
+			return nil, fmt.Errorf("неверное имя поля %q", key)
+		}
+
+		value := values[0]
+		if number, err := strconv.ParseFloat(value, 64); err == nil && op != "=" {
+			query = query.Where(
+				fmt.Sprintf("jsonb_typeof(defects.custom_fields -> ?) = 'number' AND (defects.custom_fields ->> ?)::numeric %s ?", op),
+				key, key, number)
+		} else {
+			query = query.Where(fmt.Sprintf("defects.custom_fields ->> ? %s ?", op), key, value)
+		}
+	}
+	return query, nil
+}
+
+// Display возвращает значение поля в виде строки для выгрузок.
+func Display(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,99 @@
+package delegations
+
+import (
+	"fmt"
+	"time"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Active возвращает заместителя пользователя на дату day или nil, если замещения нет.
+func Active(db *gorm.DB, userID uint, day time.Time) (*uint, error) {
+	var delegation models.Delegation
+	err := db.Where("user_id = ? AND starts_on <= ? AND ends_on >= ?", userID, day.Format("2006-01-02"), day.Format("2006-01-02")).
+		Order("starts_on DESC, id DESC").
+		Limit(1).
+		Find(&delegation).Error
+	if err != nil || delegation.ID == 0 {
+		return nil, err
+	}
+	return &delegation.DelegateID, nil
+}
+
+// Resolve возвращает того, кто фактически работает за пользователя на дату day: если заместитель
+// тоже отсутствует, задача идёт дальше по цепочке. Цепочка, замкнувшаяся на уже пройденного
+// пользователя, обрывается на нём.
+func Resolve(db *gorm.DB, userID uint, day time.Time) (uint, error) {
+	visited := map[uint]bool{userID: true}
+	current := userID
+	for {
+		delegateID, err := Active(db, current, day)
+		if err != nil {
+			return userID, err
+		}
+		if delegateID == nil || visited[*delegateID] {
+			return current, nil
+		}
+		visited[*delegateID] = true
+		current = *delegateID
+	}
+}
+
+// Delegators — подзапрос пользователей, которых userID замещает на дату day.
+func Delegators(db *gorm.DB, userID uint, day time.Time) *gorm.DB {
+	return db.Model(&models.Delegation{}).
+		Select("user_id").
+		Where("delegate_id = ? AND starts_on <= ? AND ends_on >= ?", userID, day.Format("2006-01-02"), day.Format("2006-01-02"))
+}
+
+// ActsFor проверяет, что userID сейчас замещает ownerID.
+func ActsFor(db *gorm.DB, userID, ownerID uint, day time.Time) bool {
+	var count int64
+	if err := Delegators(db, userID, day).Where("user_id = ?", ownerID).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// Validate проверяет новое замещение: заместитель активен и имеет ту же роль, период не пересекается
+// с другими замещениями пользователя.
+func Validate(db *gorm.DB, delegation models.Delegation) error {
+	if delegation.UserID == delegation.DelegateID {
+		return fmt.Errorf("нельзя назначить заместителем самого себя")
+	}
+	if delegation.EndsOn.Before(delegation.StartsOn) {
+		return fmt.Errorf("дата окончания раньше даты начала")
+	}
+
+	var user, delegate models.User
+	if err := db.First(&user, delegation.UserID).Error; err != nil {
+		return fmt.Errorf("пользователь не найден")
+	}
+	if err := db.First(&delegate, delegation.DelegateID).Error; err != nil {
+		return fmt.Errorf("заместитель не найден")
+	}
+	if delegate.Status != "active" {
+		return fmt.Errorf("заместитель деактивирован")
+	}
+	if delegate.RoleID != user.RoleID {
+		return fmt.Errorf("заместитель должен иметь ту же роль, что и замещаемый")
+	}
+
+	var overlapping int64
+	if err := db.Model(&models.Delegation{}).
+		Where("user_id = ? AND starts_on <= ? AND ends_on >= ?", delegation.UserID, delegation.EndsOn.Format("2006-01-02"), delegation.StartsOn.Format("2006-01-02")).
+		Count(&overlapping).Error; err != nil {
+		return err
+	}
+	if overlapping > 0 {
+		return fmt.Errorf("на эти даты у пользователя уже есть замещение")
+	}
+	return nil
+}
+
+// Principals — подзапрос: сам пользователь и те, кого он замещает на дату day.
+func Principals(db *gorm.DB, userID uint, day time.Time) *gorm.DB {
+	return db.Model(&models.User{}).Select("id").Where("id = ? OR id IN (?)", userID, Delegators(db, userID, day))
+}
@@ -1,9 +1,24 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
 
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/audit"
+	"systemacontrolya/internal/imports"
+	"systemacontrolya/internal/lifecycle"
+	"systemacontrolya/internal/mailer"
+	"systemacontrolya/internal/members"
 	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/organizations"
+	"systemacontrolya/internal/trash"
+	"systemacontrolya/internal/users"
 	"systemacontrolya/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -11,21 +26,43 @@ import (
 )
 
 type AdminHandler struct {
-	db *gorm.DB
+	db        *gorm.DB
+	mailer    mailer.Sender
+	retention time.Duration
 }
 
 func NewAdminHandler(db *gorm.DB) *AdminHandler {
-	return &AdminHandler{db: db}
+	return &AdminHandler{db: db, mailer: mailer.FromEnv(), retention: trash.RetentionFromEnv()}
 }
 
+// AddUser создаёт пользователя с паролем, заданным администратором. Если пароль не передан,
+// пользователь создаётся ожидающим и получает приглашение на почту, как в InviteUser.
 func (h *AdminHandler) AddUser(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
 	var input models.CreateUserInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
 		return
 	}
 
-	if len(input.Password) < 10 {
+	if err := organizations.Exists(h.db, input.OrganizationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Password == "" {
+		h.invite(c, input)
+		return
+	}
+
+	if !h.emailAvailable(c, input.Email, 0) {
+		return
+	}
+
+	if len(input.Password) < users.MinPasswordLength {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Пароль должен содержать минимум 10 символов"})
 		return
 	}
@@ -37,15 +74,23 @@ func (h *AdminHandler) AddUser(c *gin.Context) {
 	}
 
 	user := models.User{
-		FirstName:  input.FirstName,
-		LastName:   input.LastName,
-		MiddleName: input.MiddleName,
-		Email:      input.Email,
-		Password:   hashedPassword,
-		RoleID:     input.RoleID,
+		FirstName:      input.FirstName,
+		LastName:       input.LastName,
+		MiddleName:     input.MiddleName,
+		Email:          input.Email,
+		Password:       hashedPassword,
+		Status:         "active",
+		RoleID:         input.RoleID,
+		OrganizationID: input.OrganizationID,
 	}
 
-	if err := h.db.Create(&user).Error; err != nil {
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, "users", user.ID, "INSERT", actorID, nil, users.Snapshot(user), "")
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания пользователя"})
 		return
 	}
@@ -53,33 +98,62 @@ func (h *AdminHandler) AddUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Пользователь создан",
 		"user": gin.H{
-			"id":          user.ID,
-			"first_name":  user.FirstName,
-			"last_name":   user.LastName,
-			"middle_name": user.MiddleName,
-			"email":       user.Email,
-			"role":        user.RoleID,
+			"id":              user.ID,
+			"first_name":      user.FirstName,
+			"last_name":       user.LastName,
+			"middle_name":     user.MiddleName,
+			"email":           user.Email,
+			"role":            user.RoleID,
+			"organization_id": user.OrganizationID,
 		},
 	})
 }
 
 func (h *AdminHandler) AddProject(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
 	var input models.CreateProjectInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
 		return
 	}
 
+	var manager models.User
+	if err := h.db.Preload("Role").First(&manager, input.ManagerID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Менеджер не найден"})
+		return
+	}
+
 	project := models.Project{
 		Name:        input.Name,
 		ManagerID:   input.ManagerID,
 		Description: input.Description,
+		Status:      input.Status,
+	}
+	if project.Status == "" {
+		project.Status = "active"
 	}
 
-	if err := h.db.Create(&project).Error; err != nil {
+	var memberErr error
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+		_, memberErr = members.Add(tx, project.ID, manager, "manager")
+		return memberErr
+	})
+	if memberErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": memberErr.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать проект"})
 		return
 	}
+
+	project.Manager = manager
+	c.JSON(http.StatusCreated, project)
 }
 
 func (h *AdminHandler) AvaliableManagers(c *gin.Context) {
@@ -89,10 +163,10 @@ func (h *AdminHandler) AvaliableManagers(c *gin.Context) {
 		return
 	}
 
+	// Менеджер может вести несколько проектов, поэтому доступны все активные менеджеры.
 	var availableManagers []models.User
 	err := h.db.
-		Where("role_id = ?", managerRole.ID).
-		Where("id NOT IN (?)", h.db.Model(&models.Project{}).Select("manager_id")).
+		Where("role_id = ? AND status = ?", managerRole.ID, "active").
 		Find(&availableManagers).Error
 
 	if err != nil {
@@ -110,14 +184,42 @@ func (h *AdminHandler) AvaliableAssignees(c *gin.Context) {
 		return
 	}
 
-	_, exists := c.Get("userID")
+	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
 		return
 	}
 
+	// С ?project_id возвращается пул исполнителей проекта; общий список нужен только администратору.
+	// ?organization_id оставляет сотрудников организации, которой поручен дефект.
+	query := h.db.Where("role_id = ? AND status = ?", assigneeRole.ID, "active")
+	projectID, err := utils.ParseOptionalID(c.Query("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+	role, _ := c.Get("role")
+	if projectID != nil {
+		if !access.CanViewProject(h.db, *projectID, uint(userID.(float64)), role.(string)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+			return
+		}
+		query = query.Where("id IN (?)", h.db.Model(&models.ProjectMember{}).Select("user_id").Where("project_id = ? AND role = ?", *projectID, "assignee"))
+	} else if role != "Админ" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите проект"})
+		return
+	}
+	organizationID, err := utils.ParseOptionalID(c.Query("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID организации"})
+		return
+	}
+	if organizationID != nil {
+		query = query.Where("organization_id = ?", *organizationID)
+	}
+
 	var availableAssignees []models.User
-	if err := h.db.Where("role_id = ?", assigneeRole.ID).Find(&availableAssignees).Error; err != nil {
+	if err := query.Find(&availableAssignees).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список исполнителей"})
 		return
 	}
@@ -125,10 +227,11 @@ func (h *AdminHandler) AvaliableAssignees(c *gin.Context) {
 	c.JSON(http.StatusOK, availableAssignees)
 }
 
+// DeleteUser перемещает пользователя в корзину. Активный пользователь сначала деактивируется
+// с теми же проверками, что и в DeactivateUser; участие в проектах снимается, приглашения отзываются.
 func (h *AdminHandler) DeleteUser(c *gin.Context) {
-	role, exists := c.Get("role")
-	if !exists || role != "Админ" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+	actorID, ok := h.adminID(c)
+	if !ok {
 		return
 	}
 
@@ -141,53 +244,87 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	}
 
 	var user models.User
-	if err := h.db.First(&user, input.ID).Error; err != nil {
+	if err := h.db.Preload("Role").First(&user, input.ID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Такого пользователя не существует"})
 		return
 	}
-
-	var count int64
-	if err := h.db.Model(&models.Project{}).
-		Where("manager_id = ?", user.ID).
-		Count(&count).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки связей"})
+	if user.ID == actorID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя удалить собственную учётную запись"})
 		return
 	}
-
-	if count > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя удалить менеджера, он закреплен за проектом"})
-		return
+	if user.Status != "inactive" {
+		if err := users.CheckDeactivate(h.db, user); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	if err := h.db.Delete(&models.User{}, user.ID).Error; err != nil {
+	before := users.Snapshot(user)
+	now := time.Now()
+	if user.Status != "inactive" {
+		user.Status = "inactive"
+		user.DeactivatedAt = &now
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Select("status", "deactivated_at").Updates(&user).Error; err != nil {
+			return err
+		}
+		if err := members.HandOver(tx, user.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		if err := users.RevokePending(tx, user.ID, now); err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
 		return
 	}
+	audit.Record(h.db, "users", user.ID, "DELETE", actorID, before, nil, "Перемещён в корзину")
 
-	c.JSON(http.StatusOK, gin.H{"message": "Пользователь удалён"})
+	c.JSON(http.StatusOK, gin.H{"message": "Пользователь перемещён в корзину"})
 }
 
+// DeleteProject перемещает проект в корзину вместе с дефектами и отчётами. Нужно подтверждение —
+// название проекта в поле confirm. Окончательно проект удаляется после срока хранения корзины,
+// перед этим его данные выгружаются в архив.
 func (h *AdminHandler) DeleteProject(c *gin.Context) {
-	role, exists := c.Get("role")
-	if !exists || role != "Админ" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+	actorID, ok := h.adminID(c)
+	if !ok {
 		return
 	}
 
 	var input struct {
-		ID uint `json:"id" binding:"required"`
+		ID      uint   `json:"id" binding:"required"`
+		Confirm string `json:"confirm"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
 		return
 	}
 
-	if err := h.db.Delete(&models.Project{}, input.ID).Error; err != nil {
+	var project models.Project
+	if err := h.db.First(&project, input.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+	if input.Confirm != project.Name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Для удаления введите название проекта в поле confirm"})
+		return
+	}
+
+	if err := trash.DeleteProject(h.db, project, time.Now()); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
 		return
 	}
+	snapshot := map[string]any{"name": project.Name, "description": project.Description, "status": project.Status, "manager_id": project.ManagerID}
+	audit.Record(h.db, "projects", project.ID, "DELETE", actorID, snapshot, nil, "Перемещён в корзину")
 
-	c.JSON(http.StatusOK, gin.H{"message": "Проект удалён"})
+	c.JSON(http.StatusOK, gin.H{"message": "Проект перемещён в корзину", "purge_at": time.Now().Add(h.retention)})
 }
 
 func (h *AdminHandler) ListRoles(c *gin.Context) {
@@ -197,8 +334,18 @@ func (h *AdminHandler) ListRoles(c *gin.Context) {
 }
 
 func (h *AdminHandler) ListUsers(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
 	var users []models.User
-	if err := h.db.Preload("Role").Find(&users).Error; err != nil {
+	query := h.db.Preload("Role").Preload("Organization")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if organizationID := c.Query("organization_id"); organizationID != "" {
+		query = query.Where("organization_id = ?", organizationID)
+	}
+	if err := query.Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки пользователей"})
 		return
 	}
@@ -206,10 +353,894 @@ func (h *AdminHandler) ListUsers(c *gin.Context) {
 }
 
 func (h *AdminHandler) ListProjects(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
 	var projects []models.Project
-	if err := h.db.Preload("Manager").Find(&projects).Error; err != nil {
+	if err := h.db.Preload("Manager", models.WithDeleted).Find(&projects).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки проектов"})
 		return
 	}
 	c.JSON(http.StatusOK, projects)
 }
+
+func (h *AdminHandler) EditUser(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	var input models.UpdateUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	if !h.emailAvailable(c, input.Email, user.ID) {
+		return
+	}
+
+	if err := organizations.Exists(h.db, input.OrganizationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := users.Snapshot(user)
+	user.FirstName = input.FirstName
+	user.LastName = input.LastName
+	user.MiddleName = input.MiddleName
+	user.Email = input.Email
+	user.OrganizationID = input.OrganizationID
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Select("first_name", "last_name", "middle_name", "email", "organization_id").Updates(&user).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, "users", user.ID, "UPDATE", actorID, before, users.Snapshot(user), "Изменение данных")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить пользователя"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) ChangeUserRole(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	var input models.ChangeRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+	if user.ID == actorID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя изменить собственную роль"})
+		return
+	}
+
+	var role models.Role
+	if err := h.db.First(&role, input.RoleID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Роль не найдена"})
+		return
+	}
+	if role.ID == user.RoleID {
+		c.JSON(http.StatusOK, user)
+		return
+	}
+	if err := users.CheckRoleChange(h.db, user, role); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := users.Snapshot(user)
+	user.RoleID = role.ID
+	user.Role = role
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("role_id", role.ID).Error; err != nil {
+			return err
+		}
+		if err := members.DropIncompatible(tx, user.ID, role.Name); err != nil {
+			return err
+		}
+		return audit.Record(tx, "users", user.ID, "UPDATE", actorID, before, users.Snapshot(user), "Смена роли на «"+role.Name+"»")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось изменить роль"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) DeactivateUser(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	h.deactivate(c, uint(userID))
+}
+
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	if user.Status != "inactive" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Пользователь не деактивирован"})
+		return
+	}
+
+	before := users.Snapshot(user)
+	user.Status = "active"
+	user.DeactivatedAt = nil
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Select("status", "deactivated_at").Updates(&user).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, "users", user.ID, "UPDATE", actorID, before, users.Snapshot(user), "Повторная активация")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось активировать пользователя"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) UserHistory(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	history, err := audit.History(h.db, "users", user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить историю"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user, "history": history})
+}
+
+// ImportUsers создаёт пользователей из CSV или XLSX. Колонки: email, фамилия, имя, отчество, роль;
+// roles — необязательное сопоставление значений колонки «Роль» с ролями системы.
+// mode=password выдаёт сгенерированные пароли, mode=invite — ссылки-приглашения.
+// Без confirm=true выполняется только проверка файла.
+func (h *AdminHandler) ImportUsers(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+
+	mode := c.DefaultPostForm("mode", "password")
+	if mode != "password" && mode != "invite" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Режим должен быть 'password' или 'invite'"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не передан"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось открыть файл"})
+		return
+	}
+	defer file.Close()
+
+	table, err := imports.Read(fileHeader.Filename, file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	roles, err := users.NewRoleMapper(h.db, c.PostForm("roles"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rows, err := users.CheckImport(h.db, table, roles)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invalid := 0
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			invalid++
+		}
+	}
+	report := gin.H{"mode": mode, "total": len(rows), "valid": len(rows) - invalid, "invalid": invalid, "rows": rows}
+
+	if c.PostForm("confirm") != "true" {
+		report["dry_run"] = true
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	if invalid > 0 {
+		report["error"] = "В файле есть строки с ошибками, импорт не выполнен"
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	type pendingInvitation struct {
+		invitation models.Invitation
+		user       models.User
+		token      string
+		result     gin.H
+	}
+	var invitations []pendingInvitation
+
+	created := make([]gin.H, 0, len(rows))
+	now := time.Now()
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			user := models.User{
+				Email:      row.Email,
+				FirstName:  row.FirstName,
+				LastName:   row.LastName,
+				MiddleName: row.MiddleName,
+				Status:     "active",
+				RoleID:     row.RoleID,
+			}
+			result := gin.H{"line": row.Line, "email": row.Email, "role": row.Role}
+
+			var password string
+			var err error
+			if mode == "password" {
+				if password, err = users.GeneratePassword(); err != nil {
+					return err
+				}
+				user.Password, err = utils.HashPassword(password)
+				result["password"] = password
+			} else {
+				user.Status = "pending"
+				user.Password, err = users.UnusablePassword()
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			result["id"] = user.ID
+
+			if mode == "invite" {
+				invitation, token, err := users.NewInvitation(tx, user.ID, actorID, now)
+				if err != nil {
+					return err
+				}
+				result["invite_link"] = users.InvitationLink(token)
+				result["expires_at"] = invitation.ExpiresAt
+				invitations = append(invitations, pendingInvitation{invitation, user, token, result})
+			}
+
+			if err := audit.Record(tx, "users", user.ID, "INSERT", actorID, nil, users.Snapshot(user), "Импорт из файла "+fileHeader.Filename); err != nil {
+				return err
+			}
+			created = append(created, result)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка импорта пользователей"})
+		return
+	}
+
+	// Письма уходят после фиксации транзакции: неотправленное приглашение можно выслать повторно.
+	for _, pending := range invitations {
+		pending.result["sent"] = users.SendInvitation(h.db, h.mailer, &pending.invitation, pending.user, pending.token) == nil
+	}
+
+	report["created"] = created
+	c.JSON(http.StatusCreated, report)
+}
+
+// deactivate закрывает пользователю вход, сохраняя его данные. Открытые дефекты остаются на нём,
+// их число возвращается, чтобы менеджеры могли их переназначить.
+func (h *AdminHandler) deactivate(c *gin.Context, userID uint) {
+	actorID, _ := c.Get("userID")
+
+	var user models.User
+	if err := h.db.Preload("Role").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Такого пользователя не существует"})
+		return
+	}
+	if user.ID == uint(actorID.(float64)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя деактивировать собственную учётную запись"})
+		return
+	}
+	if user.Status == "inactive" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Пользователь уже деактивирован"})
+		return
+	}
+	if err := users.CheckDeactivate(h.db, user); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := users.Snapshot(user)
+	now := time.Now()
+	user.Status = "inactive"
+	user.DeactivatedAt = &now
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Select("status", "deactivated_at").Updates(&user).Error; err != nil {
+			return err
+		}
+		if err := members.HandOver(tx, user.ID); err != nil {
+			return err
+		}
+		return audit.Record(tx, "users", user.ID, "UPDATE", uint(actorID.(float64)), before, users.Snapshot(user), "Деактивация")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось деактивировать пользователя"})
+		return
+	}
+
+	openDefects, _ := users.OpenAssignments(h.db, user.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Пользователь деактивирован",
+		"user":         user,
+		"open_defects": openDefects,
+	})
+}
+
+// emailAvailable отвечает 400, если email занят другим пользователем.
+func (h *AdminHandler) emailAvailable(c *gin.Context, email string, exceptID uint) bool {
+	taken, err := users.EmailTaken(h.db, email, exceptID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось проверить email"})
+		return false
+	}
+	if taken {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Пользователь с таким email уже существует"})
+		return false
+	}
+	return true
+}
+
+func (h *AdminHandler) adminID(c *gin.Context) (uint, bool) {
+	role, _ := c.Get("role")
+	userID, exists := c.Get("userID")
+	if !exists || role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return 0, false
+	}
+	return uint(userID.(float64)), true
+}
+
+func (h *AdminHandler) findUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return user, false
+	}
+	if err := h.db.Preload("Role").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Такого пользователя не существует"})
+		return user, false
+	}
+	return user, true
+}
+
+func (h *AdminHandler) InviteUser(c *gin.Context) {
+	var input models.CreateUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	h.invite(c, input)
+}
+
+func (h *AdminHandler) ListInvitations(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
+
+	var invitations []models.Invitation
+	if err := h.db.Preload("User.Role").Order("created_at DESC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить приглашения"})
+		return
+	}
+
+	now := time.Now()
+	status := c.Query("status")
+	result := make([]models.Invitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		invitation.Status = users.InvitationStatus(invitation, now)
+		if status == "" || invitation.Status == status {
+			result = append(result, invitation)
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ResendInvitation выпускает новую ссылку взамен прежней: старые ссылки пользователя отзываются,
+// срок действия отсчитывается заново.
+func (h *AdminHandler) ResendInvitation(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	previous, ok := h.findInvitation(c)
+	if !ok {
+		return
+	}
+	if previous.AcceptedAt != nil || previous.User.Status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Приглашение уже принято"})
+		return
+	}
+
+	now := time.Now()
+	var invitation models.Invitation
+	var token string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := users.RevokePending(tx, previous.UserID, now); err != nil {
+			return err
+		}
+		var err error
+		invitation, token, err = users.NewInvitation(tx, previous.UserID, actorID, now)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать приглашение"})
+		return
+	}
+
+	h.sendInvitation(c, http.StatusOK, invitation, previous.User, token)
+}
+
+func (h *AdminHandler) RevokeInvitation(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
+	invitation, ok := h.findInvitation(c)
+	if !ok {
+		return
+	}
+	if status := users.InvitationStatus(invitation, time.Now()); status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Отозвать можно только действующее приглашение"})
+		return
+	}
+
+	now := time.Now()
+	invitation.RevokedAt = &now
+	if err := h.db.Model(&invitation).Update("revoked_at", now).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось отозвать приглашение"})
+		return
+	}
+
+	invitation.Status = "revoked"
+	c.JSON(http.StatusOK, invitation)
+}
+
+// invite создаёт ожидающего пользователя с ролью и отправляет ему приглашение.
+// Пароль задаёт сам пользователь через /api/auth/accept-invite.
+func (h *AdminHandler) invite(c *gin.Context, input models.CreateUserInput) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	if !h.emailAvailable(c, input.Email, 0) {
+		return
+	}
+
+	var role models.Role
+	if err := h.db.First(&role, input.RoleID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Роль не найдена"})
+		return
+	}
+
+	password, err := users.UnusablePassword()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка хеширования пароля"})
+		return
+	}
+
+	user := models.User{
+		FirstName:      input.FirstName,
+		LastName:       input.LastName,
+		MiddleName:     input.MiddleName,
+		Email:          input.Email,
+		Password:       password,
+		Status:         "pending",
+		RoleID:         role.ID,
+		Role:           role,
+		OrganizationID: input.OrganizationID,
+	}
+
+	var invitation models.Invitation
+	var token string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if invitation, token, err = users.NewInvitation(tx, user.ID, actorID, time.Now()); err != nil {
+			return err
+		}
+		return audit.Record(tx, "users", user.ID, "INSERT", actorID, nil, users.Snapshot(user), "Приглашение")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания пользователя"})
+		return
+	}
+
+	h.sendInvitation(c, http.StatusCreated, invitation, user, token)
+}
+
+// sendInvitation отправляет письмо и отвечает приглашением. Если почта недоступна, приглашение
+// остаётся действующим: в ответе sent=false, письмо можно выслать повторно.
+func (h *AdminHandler) sendInvitation(c *gin.Context, status int, invitation models.Invitation, user models.User, token string) {
+	sent := true
+	response := gin.H{}
+	if err := users.SendInvitation(h.db, h.mailer, &invitation, user, token); err != nil {
+		sent = false
+		response["error"] = "Не удалось отправить письмо с приглашением"
+	}
+
+	invitation.User = user
+	invitation.Status = users.InvitationStatus(invitation, time.Now())
+	response["invitation"] = invitation
+	response["sent"] = sent
+	c.JSON(status, response)
+}
+
+func (h *AdminHandler) findInvitation(c *gin.Context) (models.Invitation, bool) {
+	var invitation models.Invitation
+	invitationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID приглашения"})
+		return invitation, false
+	}
+	if err := h.db.Preload("User").First(&invitation, invitationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Приглашение не найдено"})
+		return invitation, false
+	}
+	return invitation, true
+}
+
+func (h *AdminHandler) ListMembers(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+
+	var projectMembers []models.ProjectMember
+	if err := h.db.Preload("User.Role").Where("project_id = ?", project.ID).Order("role, id").Find(&projectMembers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить участников проекта"})
+		return
+	}
+
+	c.JSON(http.StatusOK, projectMembers)
+}
+
+// SetMember добавляет пользователя в проект или меняет его роль в проекте.
+func (h *AdminHandler) SetMember(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+
+	var input models.ProjectMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.Preload("Role").First(&user, input.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Такого пользователя не существует"})
+		return
+	}
+
+	var member models.ProjectMember
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		member, err = members.Add(tx, project.ID, user, input.Role)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	audit.Record(h.db, "project_members", member.ID, "INSERT", actorID, nil, gin.H{"project_id": project.ID, "user_id": user.ID, "role": member.Role}, "")
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *AdminHandler) RemoveMember(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		member, err := members.Remove(tx, project.ID, uint(userID))
+		if err != nil {
+			return err
+		}
+		return audit.Record(tx, "project_members", member.ID, "DELETE", actorID, gin.H{"project_id": project.ID, "user_id": member.UserID, "role": member.Role}, nil, "")
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Пользователь исключён из проекта"})
+}
+
+func (h *AdminHandler) findProject(c *gin.Context) (models.Project, bool) {
+	var project models.Project
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return project, false
+	}
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return project, false
+	}
+	return project, true
+}
+
+func (h *AdminHandler) UpdateProject(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, project.ID) {
+		return
+	}
+
+	var input models.UpdateProjectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	before := map[string]any{"name": project.Name, "description": project.Description}
+	project.Name = input.Name
+	project.Description = input.Description
+	if err := h.db.Model(&project).Select("name", "description").Updates(&project).Error; err != nil {
+		if utils.IsUniqueViolation(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Проект с таким названием уже существует"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить проект"})
+		return
+	}
+	audit.Record(h.db, "projects", project.ID, "UPDATE", actorID, before, map[string]any{"name": project.Name, "description": project.Description}, "")
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *AdminHandler) ChangeProjectStatus(c *gin.Context) {
+	var input models.ProjectStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	h.setProjectStatus(c, func(project models.Project) (string, bool) {
+		if project.Status == "archived" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Проект в архиве, сначала восстановите его"})
+			return "", false
+		}
+		if project.Status != input.Status && !slices.Contains(lifecycle.Transitions[project.Status], input.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Недопустимый переход статуса проекта"})
+			return "", false
+		}
+		return input.Status, true
+	})
+}
+
+// CloseOutProject показывает, что мешает закрыть проект.
+func (h *AdminHandler) CloseOutProject(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+
+	report, err := lifecycle.CloseOut(h.db, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось проверить проект"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ArchiveProject переводит проект в архив, если проверка закрытия пройдена. Архивный проект доступен только для чтения.
+func (h *AdminHandler) ArchiveProject(c *gin.Context) {
+	h.setProjectStatus(c, func(project models.Project) (string, bool) {
+		if project.Status == "archived" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Проект уже в архиве"})
+			return "", false
+		}
+		report, err := lifecycle.CloseOut(h.db, project.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось проверить проект"})
+			return "", false
+		}
+		if !report.Ready {
+			c.JSON(http.StatusConflict, gin.H{"error": "Проект нельзя закрыть", "closeout": report})
+			return "", false
+		}
+		return "archived", true
+	})
+}
+
+// RestoreProject возвращает проект из архива; по умолчанию в статус гарантии.
+func (h *AdminHandler) RestoreProject(c *gin.Context) {
+	var input struct {
+		Status string `json:"status" binding:"omitempty,oneof=planning active warranty"`
+	}
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+	if input.Status == "" {
+		input.Status = "warranty"
+	}
+
+	h.setProjectStatus(c, func(project models.Project) (string, bool) {
+		if project.Status != "archived" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Проект не в архиве"})
+			return "", false
+		}
+		return input.Status, true
+	})
+}
+
+func (h *AdminHandler) DownloadExport(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
+
+	filename := filepath.Base(c.Param("filename"))
+	filePath := filepath.Join(lifecycle.ExportDir, filename)
+	if _, err := os.Stat(filePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Выгрузка не найдена"})
+		return
+	}
+
+	c.FileAttachment(filePath, filename)
+}
+
+// setProjectStatus применяет смену статуса, которую решает next, и пишет её в журнал.
+func (h *AdminHandler) setProjectStatus(c *gin.Context, next func(project models.Project) (string, bool)) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+
+	status, ok := next(project)
+	if !ok {
+		return
+	}
+
+	before := project.Status
+	project.Status = status
+	project.ArchivedAt = nil
+	if status == "archived" {
+		now := time.Now()
+		project.ArchivedAt = &now
+	}
+	if err := h.db.Model(&project).Select("status", "archived_at").Updates(&project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось изменить статус проекта"})
+		return
+	}
+	audit.Record(h.db, "projects", project.ID, "UPDATE", actorID, map[string]any{"status": before}, map[string]any{"status": status}, "")
+
+	c.JSON(http.StatusOK, project)
+}
+
+// ListTrash возвращает записи корзины; ?kind= ограничивает вид: defects, reports, users или projects.
+func (h *AdminHandler) ListTrash(c *gin.Context) {
+	if _, ok := h.adminID(c); !ok {
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && !slices.Contains(trash.Kinds, kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный вид записей"})
+		return
+	}
+
+	items, err := trash.List(h.db, kind, h.retention)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить корзину"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items, "retention_days": int(h.retention.Hours() / 24)})
+}
+
+// RestoreTrash возвращает запись из корзины. Восстановленный пользователь остаётся деактивированным.
+func (h *AdminHandler) RestoreTrash(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+
+	kind := c.Param("kind")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || !slices.Contains(trash.Kinds, kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный адрес записи"})
+		return
+	}
+
+	err = trash.Restore(h.db, kind, uint(id))
+	if errors.Is(err, trash.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Запись в корзине не найдена"})
+		return
+	}
+	var conflict *trash.ConflictError
+	if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": conflict.Reason})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось восстановить запись"})
+		return
+	}
+	audit.Record(h.db, kind, uint(id), "UPDATE", actorID, nil, nil, "Восстановлен из корзины")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Запись восстановлена"})
+}
@@ -14,11 +14,33 @@ func (h *AdminHandler) RegisterRoutes(router *gin.Engine) {
 		admin.GET("/projects", utils.AuthMiddleware(), h.ListProjects)
 		admin.GET("/available_managers", utils.AuthMiddleware(), h.AvaliableManagers)
 		admin.GET("/available_assignees", utils.AuthMiddleware(), h.AvaliableAssignees)
+		admin.GET("/users/:id/history", utils.AuthMiddleware(), h.UserHistory)
+		admin.GET("/invitations", utils.AuthMiddleware(), h.ListInvitations)
+		admin.GET("/projects/:id/members", utils.AuthMiddleware(), h.ListMembers)
+		admin.GET("/projects/:id/closeout", utils.AuthMiddleware(), h.CloseOutProject)
+		admin.GET("/exports/:filename", utils.AuthMiddleware(), h.DownloadExport)
+		admin.GET("/trash", utils.AuthMiddleware(), h.ListTrash)
 
 		admin.POST("/add/project", utils.AuthMiddleware(), h.AddProject)
 		admin.POST("/add/user", utils.AuthMiddleware(), h.AddUser)
+		admin.POST("/users/import", utils.AuthMiddleware(), h.ImportUsers)
+		admin.POST("/users/:id/deactivate", utils.AuthMiddleware(), h.DeactivateUser)
+		admin.POST("/users/:id/reactivate", utils.AuthMiddleware(), h.ReactivateUser)
+		admin.POST("/invitations", utils.AuthMiddleware(), h.InviteUser)
+		admin.POST("/invitations/:id/resend", utils.AuthMiddleware(), h.ResendInvitation)
+		admin.POST("/invitations/:id/revoke", utils.AuthMiddleware(), h.RevokeInvitation)
+		admin.POST("/projects/:id/members", utils.AuthMiddleware(), h.SetMember)
+		admin.POST("/projects/:id/archive", utils.AuthMiddleware(), h.ArchiveProject)
+		admin.POST("/projects/:id/restore", utils.AuthMiddleware(), h.RestoreProject)
+		admin.POST("/trash/:kind/:id/restore", utils.AuthMiddleware(), h.RestoreTrash)
+
+		admin.PUT("/users/:id", utils.AuthMiddleware(), h.EditUser)
+		admin.PUT("/users/:id/role", utils.AuthMiddleware(), h.ChangeUserRole)
+		admin.PUT("/projects/:id", utils.AuthMiddleware(), h.UpdateProject)
+		admin.PUT("/projects/:id/status", utils.AuthMiddleware(), h.ChangeProjectStatus)
 
 		admin.DELETE("/delete/user", utils.AuthMiddleware(), h.DeleteUser)
 		admin.DELETE("/delete/project", utils.AuthMiddleware(), h.DeleteProject)
+		admin.DELETE("/projects/:id/members/:user_id", utils.AuthMiddleware(), h.RemoveMember)
 	}
 }
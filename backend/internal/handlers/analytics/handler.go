@@ -0,0 +1,302 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/analytics"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/stats"
+	"systemacontrolya/internal/xlsxexport"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AnalyticsHandler struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsHandler(db *gorm.DB) *AnalyticsHandler {
+	return &AnalyticsHandler{db: db}
+}
+
+// LeaderDurations — среднее и процентили времени до назначения, устранения и закрытия.
+func (h *AnalyticsHandler) LeaderDurations(c *gin.Context) {
+	filter, ok := h.leaderFilter(c)
+	if !ok {
+		return
+	}
+
+	durations, err := analytics.Durations(h.db, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить аналитику"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filter": filter, "durations": durations})
+}
+
+// LeaderAging — открытые дефекты по возрасту и приоритету.
+func (h *AnalyticsHandler) LeaderAging(c *gin.Context) {
+	filter, ok := h.leaderFilter(c)
+	if !ok {
+		return
+	}
+
+	buckets, err := analytics.Aging(h.db, filter, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить аналитику"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filter": filter, "buckets": buckets})
+}
+
+// LeaderThroughput — созданные и закрытые дефекты по неделям и остаток открытых для графика сгорания.
+func (h *AnalyticsHandler) LeaderThroughput(c *gin.Context) {
+	filter, ok := h.leaderFilter(c)
+	if !ok {
+		return
+	}
+
+	weeks, err := analytics.Throughput(h.db, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить аналитику"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filter": filter, "weeks": weeks})
+}
+
+// LeaderSnapshots — ежедневные снимки числа дефектов по статусам и просроченных из хранилища статистики.
+func (h *AnalyticsHandler) LeaderSnapshots(c *gin.Context) {
+	filter, ok := h.leaderFilter(c)
+	if !ok {
+		return
+	}
+
+	refreshedAt, err := stats.Freshness(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить аналитику"})
+		return
+	}
+	days, err := stats.Daily(h.db, filter.ProjectID, filter.From, filter.To)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить аналитику"})
+		return
+	}
+
+	c.Header(stats.FreshnessHeader, stats.FormatFreshness(refreshedAt))
+	c.JSON(http.StatusOK, gin.H{"filter": filter, "days": days, "refreshed_at": refreshedAt})
+}
+
+// AssigneeWorkload — нагрузка исполнителей: руководитель видит всю организацию, менеджер — свои проекты.
+func (h *AnalyticsHandler) AssigneeWorkload(c *gin.Context) {
+	filter, ok := h.workloadFilter(c)
+	if !ok {
+		return
+	}
+
+	rows, err := analytics.AssigneeWorkload(h.db, filter, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить нагрузку"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filter": filter, "assignees": rows})
+}
+
+// OrganizationWorkload — нагрузка и результативность подрядных организаций с тем же разграничением доступа.
+func (h *AnalyticsHandler) OrganizationWorkload(c *gin.Context) {
+	filter, ok := h.workloadFilter(c)
+	if !ok {
+		return
+	}
+
+	rows, err := analytics.OrganizationWorkload(h.db, filter, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить нагрузку"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filter": filter, "organizations": rows})
+}
+
+// ManagerWorkload — нагрузка ведущих менеджеров проектов с тем же разграничением доступа.
+func (h *AnalyticsHandler) ManagerWorkload(c *gin.Context) {
+	filter, ok := h.workloadFilter(c)
+	if !ok {
+		return
+	}
+
+	rows, err := analytics.ManagerWorkload(h.db, filter, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить нагрузку"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filter": filter, "managers": rows})
+}
+
+// TimeReport — трудозатраты по периодам, проектам и исполнителям с тем же разграничением доступа.
+// group задаёт шаг (day, week, month, по умолчанию week), format=csv или xlsx отдаёт файл для расчёта
+// зарплаты и выставления счетов.
+func (h *AnalyticsHandler) TimeReport(c *gin.Context) {
+	filter, ok := h.workloadFilter(c)
+	if !ok {
+		return
+	}
+	group := c.DefaultQuery("group", "week")
+	if _, known := models.TimePeriodNames[group]; !known {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный период группировки"})
+		return
+	}
+
+	rows, err := analytics.TimeReport(h.db, filter, group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить трудозатраты"})
+		return
+	}
+
+	filename := fmt.Sprintf("time_%s_%s", filter.From.Format("2006-01-02"), filter.To.AddDate(0, 0, -1).Format("2006-01-02"))
+	switch c.Query("format") {
+	case "csv":
+		records := make([][]string, 0, len(rows))
+		for _, row := range rows {
+			records = append(records, []string{
+				row.Period.Format("2006-01-02"),
+				row.ProjectName,
+				row.UserName,
+				strconv.FormatFloat(row.Hours, 'f', 2, 64),
+				strconv.FormatInt(row.Entries, 10),
+				strconv.FormatInt(row.Defects, 10),
+			})
+		}
+		writeCSV(c, filename, []string{"Период", "Проект", "Исполнитель", "Часы", "Записей", "Дефектов"}, records)
+	case "xlsx":
+		b := &bytes.Buffer{}
+		if err := xlsxexport.WriteTimeReport(rows, b); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать файл"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", filename))
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", b.Bytes())
+	default:
+		var total float64
+		for _, row := range rows {
+			total += row.Hours
+		}
+		c.JSON(http.StatusOK, gin.H{"filter": filter, "group": group, "rows": rows, "total_hours": total})
+	}
+}
+
+// costGroupTitles — разрезы свода затрат и заголовки первой колонки в выгрузке.
+var costGroupTitles = map[string]string{
+	"project":      "Проект",
+	"location":     "Место",
+	"cause":        "Причина",
+	"organization": "Организация",
+	"party":        "Ответственная сторона",
+}
+
+// Costs — свод затрат на устранение дефектов, выявленных в периоде: by задаёт разрез (project, location,
+// cause, organization, party, по умолчанию project), format=csv или xlsx отдаёт файл. Доступ как у нагрузки.
+func (h *AnalyticsHandler) Costs(c *gin.Context) {
+	filter, ok := h.workloadFilter(c)
+	if !ok {
+		return
+	}
+	by := c.DefaultQuery("by", "project")
+	title, known := costGroupTitles[by]
+	if !known {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный разрез затрат"})
+		return
+	}
+
+	rows, err := analytics.Costs(h.db, filter, by)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить затраты"})
+		return
+	}
+
+	filename := fmt.Sprintf("costs_%s_%s_%s", by, filter.From.Format("2006-01-02"), filter.To.AddDate(0, 0, -1).Format("2006-01-02"))
+	switch c.Query("format") {
+	case "csv":
+		records := make([][]string, 0, len(rows))
+		for _, row := range rows {
+			records = append(records, []string{
+				row.Name,
+				strconv.FormatInt(row.Defects, 10),
+				strconv.FormatFloat(row.EstimatedLabour, 'f', 2, 64),
+				strconv.FormatFloat(row.ActualLabour, 'f', 2, 64),
+				strconv.FormatFloat(row.Materials, 'f', 2, 64),
+				strconv.FormatFloat(row.Total, 'f', 2, 64),
+			})
+		}
+		writeCSV(c, filename, []string{title, "Дефектов", "Работы (оценка)", "Работы (факт)", "Материалы", "Итого"}, records)
+	case "xlsx":
+		b := &bytes.Buffer{}
+		if err := xlsxexport.WriteCostReport(title, rows, b); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать файл"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", filename))
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", b.Bytes())
+	default:
+		c.JSON(http.StatusOK, gin.H{"filter": filter, "by": by, "rows": rows})
+	}
+}
+
+// writeCSV отдаёт таблицу файлом CSV для Excel: UTF-8 с BOM и разделитель «;».
+func writeCSV(c *gin.Context, filename string, header []string, rows [][]string) {
+	b := &bytes.Buffer{}
+	b.Write([]byte{0xEF, 0xBB, 0xBF})
+
+	writer := csv.NewWriter(b)
+	writer.Comma = ';'
+	writer.Write(header)
+	writer.WriteAll(rows)
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", b.Bytes())
+}
+
+func (h *AnalyticsHandler) workloadFilter(c *gin.Context) (analytics.Filter, bool) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if role != "Руководитель" && role != "Менеджер" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещён. Требуется роль Руководителя или Менеджера"})
+		return analytics.Filter{}, false
+	}
+
+	filter, err := analytics.ParseFilter(c.Request.URL.Query(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return filter, false
+	}
+	if role == "Менеджер" {
+		filter = filter.Within(access.MemberProjects(h.db, uint(userID.(float64)), "manager"))
+	}
+	return filter, true
+}
+
+func (h *AnalyticsHandler) leaderFilter(c *gin.Context) (analytics.Filter, bool) {
+	role, exists := c.Get("role")
+	if !exists || role != "Руководитель" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещён. Требуется роль Руководителя"})
+		return analytics.Filter{}, false
+	}
+
+	filter, err := analytics.ParseFilter(c.Request.URL.Query(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return filter, false
+	}
+	return filter, true
+}
@@ -0,0 +1,22 @@
+package analytics
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *AnalyticsHandler) RegisterRoutes(router *gin.Engine) {
+	analytics := router.Group("api/analytics")
+	{
+		analytics.GET("/durations", utils.AuthMiddleware(), h.LeaderDurations)
+		analytics.GET("/aging", utils.AuthMiddleware(), h.LeaderAging)
+		analytics.GET("/throughput", utils.AuthMiddleware(), h.LeaderThroughput)
+		analytics.GET("/snapshots", utils.AuthMiddleware(), h.LeaderSnapshots)
+		analytics.GET("/workload/assignees", utils.AuthMiddleware(), h.AssigneeWorkload)
+		analytics.GET("/workload/managers", utils.AuthMiddleware(), h.ManagerWorkload)
+		analytics.GET("/workload/organizations", utils.AuthMiddleware(), h.OrganizationWorkload)
+		analytics.GET("/time", utils.AuthMiddleware(), h.TimeReport)
+		analytics.GET("/costs", utils.AuthMiddleware(), h.Costs)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"os"
 	"time"
 
+	"systemacontrolya/internal/audit"
 	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/users"
 	"systemacontrolya/internal/utils"
 
 	"github.com/dgrijalva/jwt-go"
@@ -44,6 +46,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if user.Status == "pending" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Учётная запись не активирована: задайте пароль по ссылке из приглашения"})
+		return
+	}
+	if user.Status != "active" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Учётная запись деактивирована"})
+		return
+	}
+
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"id":   user.ID,
 		"role": user.Role.Name,
@@ -102,12 +113,17 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	userID := uint(claims["id"].(float64))
-	role := claims["role"].(string)
+	// Роль и статус берутся из базы, а не из токена: администратор мог сменить роль или деактивировать пользователя.
+	var user models.User
+	if err := h.db.Preload("Role").First(&user, uint(claims["id"].(float64))).Error; err != nil || user.Status != "active" {
+		c.SetCookie("refresh_token", "", -1, "/", "", true, true)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Учётная запись недоступна"})
+		return
+	}
 
 	newAccess := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":   userID,
-		"role": role,
+		"id":   user.ID,
+		"role": user.Role.Name,
 		"exp":  time.Now().Add(time.Minute * 15).Unix(),
 	})
 
@@ -122,6 +138,75 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	})
 }
 
+// AcceptInvite завершает регистрацию по ссылке-приглашению: пользователь задаёт пароль,
+// учётная запись становится активной, а приглашение — использованным.
+func (h *AuthHandler) AcceptInvite(c *gin.Context) {
+	var input models.AcceptInviteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+	if len(input.Password) < users.MinPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Пароль должен содержать минимум 10 символов"})
+		return
+	}
+
+	var invitation models.Invitation
+	if err := h.db.Preload("User").Where("token_hash = ?", users.HashToken(input.Token)).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Приглашение не найдено"})
+		return
+	}
+	now := time.Now()
+	if invitation.AcceptedAt != nil || invitation.User.Status != "pending" {
+		c.JSON(http.StatusGone, gin.H{"error": "Приглашение уже использовано"})
+		return
+	}
+	if invitation.RevokedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Приглашение отозвано"})
+		return
+	}
+	if now.After(invitation.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Срок действия приглашения истёк"})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(input.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка хеширования пароля"})
+		return
+	}
+
+	user := invitation.User
+	before := users.Snapshot(user)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Условие на accepted_at не даёт использовать одну ссылку дважды при параллельных запросах.
+		result := tx.Model(&invitation).Where("accepted_at IS NULL AND revoked_at IS NULL").Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		user.Password = hashedPassword
+		user.Status = "active"
+		if err := tx.Model(&user).Select("password", "status").Updates(&user).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, "users", user.ID, "UPDATE", user.ID, before, users.Snapshot(user), "Принятие приглашения")
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusGone, gin.H{"error": "Приглашение уже использовано"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось принять приглашение"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Регистрация завершена, войдите с новым паролем", "email": user.Email})
+}
+
 func (h *AuthHandler) Check(c *gin.Context) {
 	userID, _ := c.Get("userID")
 	role, _ := c.Get("role")
@@ -13,5 +13,6 @@ func (h *AuthHandler) RegisterRoutes(router *gin.Engine) {
 		auth.POST("/refresh", h.Refresh)
 		auth.GET("/check_token", utils.AuthMiddleware(), h.Check)
 		auth.POST("/logout", h.Logout)
+		auth.POST("/accept-invite", h.AcceptInvite)
 	}
 }
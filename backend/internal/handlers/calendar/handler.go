@@ -0,0 +1,255 @@
+package calendar
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/calendar"
+	"systemacontrolya/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CalendarHandler struct {
+	db *gorm.DB
+}
+
+func NewCalendarHandler(db *gorm.DB) *CalendarHandler {
+	return &CalendarHandler{db: db}
+}
+
+func (h *CalendarHandler) GetGlobalCalendar(c *gin.Context) {
+	var wc models.WorkCalendar
+	if err := h.db.Preload("Holidays").Where("project_id IS NULL").Order("id").First(&wc).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Общий календарь не настроен"})
+		return
+	}
+	c.JSON(http.StatusOK, wc)
+}
+
+func (h *CalendarHandler) GetProjectCalendar(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, uint(projectID), uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	var wc models.WorkCalendar
+	overridden := true
+	if err := h.db.Preload("Holidays").Where("project_id = ?", projectID).First(&wc).Error; err != nil {
+		overridden = false
+		if err := h.db.Preload("Holidays").Where("project_id IS NULL").Order("id").First(&wc).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Календарь не настроен"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"calendar": wc, "overridden": overridden})
+}
+
+func (h *CalendarHandler) UpdateGlobalCalendar(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	var wc models.WorkCalendar
+	if err := h.db.Where("project_id IS NULL").Order("id").First(&wc).Error; err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка базы данных"})
+		return
+	}
+
+	h.saveCalendar(c, &wc)
+}
+
+func (h *CalendarHandler) UpdateProjectCalendar(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+
+	var wc models.WorkCalendar
+	if err := h.db.Where("project_id = ?", project.ID).First(&wc).Error; err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка базы данных"})
+		return
+	}
+	wc.ProjectID = &project.ID
+
+	h.saveCalendar(c, &wc)
+}
+
+func (h *CalendarHandler) saveCalendar(c *gin.Context, wc *models.WorkCalendar) {
+	var input models.CalendarInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	wc.Name = input.Name
+	wc.WorkStart = input.WorkStart
+	wc.WorkEnd = input.WorkEnd
+	wc.Weekends = input.Weekends
+	if input.Timezone != "" {
+		wc.Timezone = input.Timezone
+	} else if wc.Timezone == "" {
+		wc.Timezone = "Europe/Moscow"
+	}
+
+	if _, err := time.LoadLocation(wc.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный часовой пояс"})
+		return
+	}
+	if _, err := calendar.New(*wc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	weekends := map[int]bool{}
+	for _, day := range wc.Weekends {
+		weekends[day] = true
+	}
+	if len(weekends) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите хотя бы один выходной день"})
+		return
+	}
+	if len(weekends) >= 7 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "В неделе должен быть хотя бы один рабочий день"})
+		return
+	}
+
+	if err := h.db.Save(wc).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить календарь"})
+		return
+	}
+
+	h.db.Preload("Holidays").First(wc, wc.ID)
+	c.JSON(http.StatusOK, wc)
+}
+
+func (h *CalendarHandler) DeleteProjectCalendar(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var wc models.WorkCalendar
+	if err := h.db.Where("project_id = ?", projectID).First(&wc).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "У проекта нет собственного календаря"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("calendar_id = ?", wc.ID).Delete(&models.Holiday{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&wc).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Проект переведён на общий календарь"})
+}
+
+func (h *CalendarHandler) AddHoliday(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	calendarID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID календаря"})
+		return
+	}
+
+	var wc models.WorkCalendar
+	if err := h.db.First(&wc, calendarID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Календарь не найден"})
+		return
+	}
+
+	var input models.HolidayInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", input.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Дата должна быть в формате ГГГГ-ММ-ДД"})
+		return
+	}
+
+	holiday := models.Holiday{
+		CalendarID: wc.ID,
+		Date:       date,
+		Name:       input.Name,
+		Working:    input.Working,
+		Recurring:  input.Recurring,
+	}
+
+	if err := h.db.Create(&holiday).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось добавить день"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, holiday)
+}
+
+func (h *CalendarHandler) DeleteHoliday(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	holidayID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дня"})
+		return
+	}
+
+	result := h.db.Delete(&models.Holiday{}, holidayID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "День не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "День удалён"})
+}
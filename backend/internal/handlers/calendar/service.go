@@ -0,0 +1,23 @@
+package calendar
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *CalendarHandler) RegisterRoutes(router *gin.Engine) {
+	calendar := router.Group("api/calendar")
+	{
+		calendar.GET("", utils.AuthMiddleware(), h.GetGlobalCalendar)
+		calendar.GET("/project/:project_id", utils.AuthMiddleware(), h.GetProjectCalendar)
+
+		calendar.POST("/:id/holidays", utils.AuthMiddleware(), h.AddHoliday)
+
+		calendar.PUT("", utils.AuthMiddleware(), h.UpdateGlobalCalendar)
+		calendar.PUT("/project/:project_id", utils.AuthMiddleware(), h.UpdateProjectCalendar)
+
+		calendar.DELETE("/project/:project_id", utils.AuthMiddleware(), h.DeleteProjectCalendar)
+		calendar.DELETE("/holidays/:id", utils.AuthMiddleware(), h.DeleteHoliday)
+	}
+}
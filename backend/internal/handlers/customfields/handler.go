@@ -0,0 +1,288 @@
+package customfields
+
+import (
+	"net/http"
+	"strconv"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/customfields"
+	"systemacontrolya/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CustomFieldsHandler struct {
+	db *gorm.DB
+}
+
+func NewCustomFieldsHandler(db *gorm.DB) *CustomFieldsHandler {
+	return &CustomFieldsHandler{db: db}
+}
+
+func (h *CustomFieldsHandler) ListFields(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, uint(projectID), uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	fields, err := customfields.ProjectFields(h.db, uint(projectID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить поля проекта"})
+		return
+	}
+
+	c.JSON(http.StatusOK, fields)
+}
+
+func (h *CustomFieldsHandler) AddField(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if role != "Админ" && !access.IsProjectManager(h.db, project.ID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, project.ID) {
+		return
+	}
+
+	var input models.CustomFieldInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	if !customfields.ValidKey(input.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ключ поля должен состоять из латинских букв, цифр и '_' и начинаться с буквы"})
+		return
+	}
+	if input.Type == "enum" && len(input.Options) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Для списка нужно указать варианты значений"})
+		return
+	}
+
+	field := models.CustomField{
+		ProjectID: project.ID,
+		Key:       input.Key,
+		Name:      input.Name,
+		Type:      input.Type,
+		Options:   input.Options,
+		Required:  input.Required,
+		Position:  input.Position,
+	}
+
+	if err := h.db.Create(&field).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Поле с таким ключом уже есть в проекте"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, field)
+}
+
+func (h *CustomFieldsHandler) EditField(c *gin.Context) {
+	field, ok := h.managedField(c)
+	if !ok {
+		return
+	}
+
+	var input models.CustomFieldInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	if input.Key != field.Key || input.Type != field.Type {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ключ и тип поля изменить нельзя"})
+		return
+	}
+	if field.Type == "enum" && len(input.Options) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Для списка нужно указать варианты значений"})
+		return
+	}
+
+	field.Name = input.Name
+	field.Options = input.Options
+	field.Required = input.Required
+	field.Position = input.Position
+
+	if err := h.db.Save(&field).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить поле"})
+		return
+	}
+
+	c.JSON(http.StatusOK, field)
+}
+
+func (h *CustomFieldsHandler) DeleteField(c *gin.Context) {
+	field, ok := h.managedField(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE defects SET custom_fields = custom_fields - ? WHERE project_id = ? AND jsonb_exists(custom_fields, ?)",
+			field.Key, field.ProjectID, field.Key).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&field).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Поле удалено"})
+}
+
+func (h *CustomFieldsHandler) SetDefectValues(c *gin.Context) {
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return
+	}
+
+	var defect models.Defect
+	if err := h.db.Preload("Project").First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	currentUserID := uint(userID.(float64))
+	if !access.IsProjectManager(h.db, defect.ProjectID, currentUserID) && defect.AuthorID != currentUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
+
+	var input models.DefectCustomFieldsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	values, err := customfields.Validate(h.db, defect.ProjectID, defect.CustomFields, input.Values)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	defect.CustomFields = values
+	if err := h.db.Model(&defect).Select("custom_fields").Updates(&defect).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить значения полей"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"custom_fields": values})
+}
+
+func (h *CustomFieldsHandler) FieldStats(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, uint(projectID), uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	var field models.CustomField
+	if err := h.db.Where("project_id = ? AND key = ?", projectID, c.Param("key")).First(&field).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Поле не найдено"})
+		return
+	}
+
+	// Ключ поля проверяется по ValidKey при создании, поэтому его можно подставлять в выражение напрямую.
+	query := h.db.Table("defects").Where("defects.project_id = ? AND defects.deleted_at IS NULL AND defects.custom_fields ->> ? IS NOT NULL", field.ProjectID, field.Key)
+
+	if field.Type == "number" {
+		var stats struct {
+			Count int64   `json:"count"`
+			Sum   float64 `json:"sum"`
+			Avg   float64 `json:"avg"`
+			Min   float64 `json:"min"`
+			Max   float64 `json:"max"`
+		}
+		value := "(defects.custom_fields ->> '" + field.Key + "')::numeric"
+		if err := query.Select("COUNT(*) AS count, COALESCE(SUM(" + value + "), 0) AS sum, COALESCE(AVG(" + value + "), 0) AS avg, COALESCE(MIN(" + value + "), 0) AS min, COALESCE(MAX(" + value + "), 0) AS max").
+			Scan(&stats).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику поля"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"field": field, "stats": stats})
+		return
+	}
+
+	type valueCount struct {
+		Value  string `json:"value"`
+		Total  int64  `json:"total"`
+		Open   int64  `json:"open"`
+		Closed int64  `json:"closed"`
+	}
+	var counts []valueCount
+	value := "defects.custom_fields ->> '" + field.Key + "'"
+	if err := query.Select(value + ` AS value, COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE defects.status <> 'closed') AS open,
+			COUNT(*) FILTER (WHERE defects.status = 'closed') AS closed`).
+		Group(value).
+		Order("total DESC").
+		Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику поля"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"field": field, "values": counts})
+}
+
+func (h *CustomFieldsHandler) managedField(c *gin.Context) (models.CustomField, bool) {
+	var field models.CustomField
+
+	fieldID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID поля"})
+		return field, false
+	}
+
+	if err := h.db.Preload("Project").First(&field, fieldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Поле не найдено"})
+		return field, false
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if role != "Админ" && !access.IsProjectManager(h.db, field.ProjectID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return field, false
+	}
+	if !access.RequireActiveProject(c, h.db, field.ProjectID) {
+		return field, false
+	}
+
+	return field, true
+}
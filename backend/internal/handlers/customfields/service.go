@@ -0,0 +1,22 @@
+package customfields
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *CustomFieldsHandler) RegisterRoutes(router *gin.Engine) {
+	fields := router.Group("api/custom-fields")
+	{
+		fields.GET("/project/:project_id", utils.AuthMiddleware(), h.ListFields)
+		fields.GET("/project/:project_id/stats/:key", utils.AuthMiddleware(), h.FieldStats)
+
+		fields.POST("/project/:project_id", utils.AuthMiddleware(), h.AddField)
+
+		fields.PUT("/edit/:id", utils.AuthMiddleware(), h.EditField)
+		fields.PUT("/defect/:id", utils.AuthMiddleware(), h.SetDefectValues)
+
+		fields.DELETE("/delete/:id", utils.AuthMiddleware(), h.DeleteField)
+	}
+}
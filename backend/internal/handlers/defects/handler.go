@@ -5,8 +5,25 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/assignments"
+	"systemacontrolya/internal/audit"
+	"systemacontrolya/internal/costs"
+	"systemacontrolya/internal/customfields"
+	"systemacontrolya/internal/labels"
+	"systemacontrolya/internal/links"
+	"systemacontrolya/internal/locations"
+	"systemacontrolya/internal/mailer"
 	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/organizations"
+	"systemacontrolya/internal/similar"
+	"systemacontrolya/internal/sla"
+	"systemacontrolya/internal/stats"
+	"systemacontrolya/internal/trash"
+	"systemacontrolya/internal/utils"
+	"systemacontrolya/internal/xlsxexport"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,11 +31,12 @@ import (
 )
 
 type DefectHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	mailer mailer.Sender
 }
 
 func NewDefectHandler(db *gorm.DB) *DefectHandler {
-	return &DefectHandler{db: db}
+	return &DefectHandler{db: db, mailer: mailer.FromEnv()}
 }
 
 func (h *DefectHandler) AddDefect(c *gin.Context) {
@@ -34,6 +52,14 @@ func (h *DefectHandler) AddDefect(c *gin.Context) {
 	}
 	authorID := uint(userID.(float64))
 
+	if !access.HasProjectRole(h.db, uint(projectID), authorID, "engineer", "manager") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, uint(projectID)) {
+		return
+	}
+
 	form, err := c.MultipartForm()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ошибка загрузки файлов"})
@@ -41,6 +67,58 @@ func (h *DefectHandler) AddDefect(c *gin.Context) {
 	}
 	files := form.File["attachments"]
 
+	labelIDs, err := utils.ParseIDs(c.PostForm("label_ids"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный список меток"})
+		return
+	}
+	defectLabels, err := labels.ProjectLabels(h.db, uint(projectID), labelIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	locationID, err := utils.ParseOptionalID(c.PostForm("location_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID места"})
+		return
+	}
+	if _, err := locations.ProjectLocation(h.db, uint(projectID), locationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customValues, err := customfields.ParseForm(c.PostForm("custom_fields"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	customValues, err = customfields.Validate(h.db, uint(projectID), nil, customValues)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// С check_duplicates=true дефект не создаётся, если найдены похожие: клиент показывает их
+	// и при необходимости отправляет форму повторно без проверки.
+	if c.PostForm("check_duplicates") == "true" {
+		matches, err := similar.Find(h.db, similar.Query{
+			ProjectID:   uint(projectID),
+			LocationID:  locationID,
+			Title:       title,
+			Description: description,
+			Hashes:      similar.HashUploads(files),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось проверить дубликаты"})
+			return
+		}
+		if len(matches) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Найдены похожие дефекты", "similar": matches})
+			return
+		}
+	}
+
 	var paths []string
 	for _, file := range files {
 		savePath := fmt.Sprintf("uploads/defects/%s", file.Filename)
@@ -60,12 +138,18 @@ func (h *DefectHandler) AddDefect(c *gin.Context) {
 		AuthorID:    authorID,
 		Attachments: paths,
 		DueDate:     nil,
+		Labels:      defectLabels,
+		LocationID:  locationID,
+
+		CustomFields: customValues,
 	}
+	sla.ApplyOnCreate(h.db, &defect, time.Now())
 
 	if err := h.db.Create(&defect).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания дефекта"})
 		return
 	}
+	similar.StoreHashes(h.db, defect.ID, defect.Attachments)
 
 	c.JSON(http.StatusCreated, gin.H{"defect": defect})
 }
@@ -81,11 +165,18 @@ func (h *DefectHandler) ManagerListDefects(c *gin.Context) {
 
 	managerID := uint(userID.(float64))
 
-	if err := h.db.Joins("JOIN projects ON projects.id = defects.project_id").
-		Where("projects.manager_id = ?", managerID).
+	query, err := h.filteredDefects(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := query.
+		Where("defects.project_id IN (?)", access.MemberProjects(h.db, managerID, "manager")).
 		Preload("Project").
-		Preload("Author").
-		Preload("Assignee").
+		Preload("Author", models.WithDeleted).
+		Preload("Assignee", models.WithDeleted).
+		Preload("Labels").Preload("Location").Preload("Organization").
 		Find(&defects).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить дефекты"})
 		return
@@ -105,7 +196,16 @@ func (h *DefectHandler) EngineerListDefects(c *gin.Context) {
 
 	authorID := uint(userID.(float64))
 
-	if err := h.db.Where("author_id = ?", authorID).Preload("Author").Preload("Project").Find(&defects).Error; err != nil {
+	query, err := h.filteredDefects(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := query.
+		Where("author_id = ? AND project_id IN (?)", authorID, access.MemberProjects(h.db, authorID)).
+		Preload("Author", models.WithDeleted).Preload("Project").Preload("Labels").Preload("Location").
+		Find(&defects).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки дефектов"})
 		return
 	}
@@ -130,6 +230,9 @@ func (h *DefectHandler) EngineerEditDefect(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Нельзя редактировать дефект после назначения исполнителя"})
 		return
 	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
 
 	title := c.PostForm("title")
 	description := c.PostForm("description")
@@ -144,7 +247,32 @@ func (h *DefectHandler) EngineerEditDefect(c *gin.Context) {
 	if priority != "" {
 		defect.Priority = priority
 	}
+	if raw := c.PostForm("location_id"); raw != "" {
+		locationID, err := utils.ParseOptionalID(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID места"})
+			return
+		}
+		if _, err := locations.ProjectLocation(h.db, defect.ProjectID, locationID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defect.LocationID = locationID
+	}
+	if raw := c.PostForm("custom_fields"); raw != "" {
+		customValues, err := customfields.ParseForm(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defect.CustomFields, err = customfields.Validate(h.db, defect.ProjectID, defect.CustomFields, customValues)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
+	var added []string
 	form, err := c.MultipartForm()
 	if err == nil && form.File != nil {
 		files := form.File["attachments"]
@@ -158,6 +286,7 @@ func (h *DefectHandler) EngineerEditDefect(c *gin.Context) {
 			}
 
 			defect.Attachments = append(defect.Attachments, "/uploads/defects/"+safeName)
+			added = append(added, "/uploads/defects/"+safeName)
 		}
 	}
 
@@ -165,15 +294,110 @@ func (h *DefectHandler) EngineerEditDefect(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить дефект"})
 		return
 	}
+	similar.StoreHashes(h.db, defect.ID, added)
 
 	c.JSON(http.StatusOK, gin.H{"defect": defect})
 }
 
+// SimilarDefects ищет возможные дубликаты по полям формы нового дефекта: project_id, location_id,
+// title, description и вложениям attachments. exclude_id исключает редактируемый дефект.
+func (h *DefectHandler) SimilarDefects(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	projectID, err := strconv.Atoi(c.PostForm("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+	if !access.CanViewProject(h.db, uint(projectID), uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+	locationID, err := utils.ParseOptionalID(c.PostForm("location_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID места"})
+		return
+	}
+	excludeID, err := utils.ParseOptionalID(c.PostForm("exclude_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return
+	}
+
+	query := similar.Query{
+		ProjectID:   uint(projectID),
+		LocationID:  locationID,
+		Title:       c.PostForm("title"),
+		Description: c.PostForm("description"),
+	}
+	if excludeID != nil {
+		query.ExcludeID = *excludeID
+	}
+	if form, err := c.MultipartForm(); err == nil {
+		query.Hashes = similar.HashUploads(form.File["attachments"])
+	}
+
+	matches, err := similar.Find(h.db, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось найти похожие дефекты"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"similar": matches})
+}
+
+// DeleteDefect перемещает ошибочно заведённый дефект в корзину вместе с отчётами. Удалить может менеджер
+// проекта или админ, автор — пока дефект новый и не назначен.
+func (h *DefectHandler) DeleteDefect(c *gin.Context) {
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return
+	}
+
+	userIDv, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	userID := uint(userIDv.(float64))
+
+	var defect models.Defect
+	if err := h.db.First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return
+	}
+
+	isAuthor := defect.AuthorID == userID && defect.Status == "new" && defect.AssigneeID == nil
+	if role != "Админ" && !isAuthor && !access.IsProjectManager(h.db, defect.ProjectID, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Удалить дефект может менеджер проекта или автор до назначения исполнителя"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
+
+	if err := trash.DeleteDefect(h.db, defect, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось удалить дефект"})
+		return
+	}
+	audit.Record(h.db, "defects", defect.ID, "DELETE", userID, map[string]any{"title": defect.Title, "status": defect.Status, "project_id": defect.ProjectID}, nil, "Перемещён в корзину")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Дефект перемещён в корзину"})
+}
+
 func (h *DefectHandler) AssigneeListDefects(c *gin.Context) {
 	userID, _ := c.Get("userID")
+	assigneeID := uint(userID.(float64))
+
+	query, err := h.filteredDefects(c, h.db)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var defects []models.Defect
-	if err := h.db.Where("assignee_id = ? AND status = ?", userID, "in_progress").
-		Preload("Project").Preload("Author").Find(&defects).Error; err != nil {
+	if err := query.
+		Where("assignee_id = ? AND status = ?", assigneeID, "in_progress").
+		Where("project_id IN (?)", access.MemberProjects(h.db, assigneeID)).
+		Preload("Project").Preload("Author", models.WithDeleted).Preload("Labels").Preload("Location").Find(&defects).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения дефектов"})
 		return
 	}
@@ -201,29 +425,50 @@ func (h *DefectHandler) ManagerEditDefect(c *gin.Context) {
 	}
 
 	userID, _ := c.Get("userID")
-	var project models.Project
-	if err := h.db.First(&project, defect.ProjectID).Error; err != nil || project.ManagerID != uint(userID.(float64)) {
+	if !access.IsProjectManager(h.db, defect.ProjectID, uint(userID.(float64))) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
 		return
 	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
 
+	// Сменить исполнителя можно только переназначением с указанием причины. Если впервые
+	// назначаемый исполнитель отсутствует, дефект получает его заместитель.
+	var assignee models.User
+	var delegatedFromID *uint
 	if input.AssigneeID != nil {
-		var assignee models.User
-		if err := h.db.First(&assignee, *input.AssigneeID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Исполнитель не найден"})
+		if defect.AssigneeID != nil && *defect.AssigneeID != *input.AssigneeID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Нельзя изменить назначенного исполнителя, используйте переназначение"})
 			return
 		}
-		if assignee.RoleID != 5 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Выбранный пользователь не является исполнителем"})
+		if defect.AssigneeID == nil {
+			assignee, delegatedFromID, err = assignments.Pick(h.db, defect.ProjectID, *input.AssigneeID, time.Now())
+		} else {
+			assignee, err = assignments.Check(h.db, defect.ProjectID, *input.AssigneeID)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		input.AssigneeID = &assignee.ID
 	}
 
+	// Дефект поручается организации, затем её сотруднику; первый назначенный исполнитель
+	// проверяется на принадлежность организации.
+	var newAssignee *models.User
+	if input.AssigneeID != nil && defect.AssigneeID == nil {
+		newAssignee = &assignee
+	}
+	previousOrganizationID := defect.OrganizationID
+	if err := organizations.Assign(h.db, &defect, input.OrganizationID, newAssignee); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previousStatus := defect.Status
 	if defect.AssigneeID == nil {
 		defect.AssigneeID = input.AssigneeID
-	} else if input.AssigneeID != nil && *defect.AssigneeID != *input.AssigneeID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Нельзя изменить назначенного исполнителя"})
-		return
 	}
 
 	if input.Status != "" {
@@ -235,6 +480,12 @@ func (h *DefectHandler) ManagerEditDefect(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя возвращать дефект в статус 'new'"})
 			return
 		}
+		if input.Status == "closed" && defect.Status != "closed" {
+			if err := links.CheckClose(h.db, defect.ID); err != nil {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+		}
 		defect.Status = input.Status
 	}
 
@@ -242,22 +493,176 @@ func (h *DefectHandler) ManagerEditDefect(c *gin.Context) {
 		defect.Status = "in_progress"
 	}
 
-	defect.DueDate = input.DueDate
-
+	if input.DueDate != nil && input.ClearDueDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя одновременно задать и снять срок выполнения"})
+		return
+	}
 	if input.DueDate != nil && input.DueDate.Before(time.Now()) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Срок выполнения не может быть в прошлом"})
 		return
 	}
 
-	if err := h.db.Save(&defect).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось назначить исполнителя"})
+	if input.DueDate != nil {
+		defect.DueDate = input.DueDate
+	}
+	if input.EstimateHours != nil {
+		defect.EstimateHours = input.EstimateHours
+	}
+	if input.EstimatedLabourCost != nil {
+		defect.EstimatedLabourCost = input.EstimatedLabourCost
+	}
+	if input.ActualLabourCost != nil {
+		defect.ActualLabourCost = input.ActualLabourCost
+	}
+	if input.ResponsibleParty != nil {
+		defect.ResponsibleParty = input.ResponsibleParty
+	}
+
+	now := time.Now()
+	statusChanged := defect.Status != previousStatus
+	// Срок по политике SLA выставляется при первом назначении, поэтому снятый срок не возвращается.
+	if newAssignee != nil {
+		sla.ApplyOnAssign(h.db, &defect, now)
+	}
+	if input.ClearDueDate {
+		defect.DueDate = nil
+	}
+	sla.StampStatus(&defect, previousStatus, now)
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&defect).Error; err != nil {
+			return err
+		}
+		if statusChanged {
+			return links.StatusChanged(tx, defect.ID, uint(userID.(float64)), now)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить дефект"})
 		return
 	}
+	if newAssignee != nil {
+		assignments.Record(h.db, "assign", defect, nil, previousOrganizationID, delegatedFromID, uint(userID.(float64)), "")
+	}
 
-	h.db.Preload("Assignee").First(&defect, defectID)
+	h.db.Preload("Assignee", models.WithDeleted).Preload("Organization").First(&defect, defectID)
 	c.JSON(http.StatusOK, gin.H{"defect": defect})
 }
 
+// ReassignDefect передаёт дефект другому исполнителю, например когда назначенный заболел или уволился.
+// Причина обязательна и попадает в историю назначений; оба исполнителя получают письмо.
+func (h *DefectHandler) ReassignDefect(c *gin.Context) {
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return
+	}
+
+	var input models.ReassignDefectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите исполнителя и причину переназначения"})
+		return
+	}
+
+	var defect models.Defect
+	if err := h.db.First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	actorID := uint(userID.(float64))
+	if !access.IsProjectManager(h.db, defect.ProjectID, actorID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
+	if defect.Status == "closed" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Закрытый дефект нельзя переназначить"})
+		return
+	}
+	if defect.AssigneeID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Исполнитель ещё не назначен"})
+		return
+	}
+
+	assignee, delegatedFromID, err := assignments.Pick(h.db, defect.ProjectID, input.AssigneeID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if assignee.ID == *defect.AssigneeID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Дефект уже назначен этому исполнителю"})
+		return
+	}
+
+	var previous models.User
+	if err := h.db.Scopes(models.WithDeleted).First(&previous, *defect.AssigneeID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось переназначить дефект"})
+		return
+	}
+	previousOrganizationID := defect.OrganizationID
+
+	// Организация проверяется заново, как при первом назначении: новый исполнитель должен
+	// состоять в организации дефекта или в организации, указанной в запросе.
+	defect.AssigneeID = nil
+	if err := organizations.Assign(h.db, &defect, input.OrganizationID, &assignee); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defect.AssigneeID = &assignee.ID
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&defect).Select("assignee_id", "organization_id").Updates(&defect).Error; err != nil {
+			return err
+		}
+		return assignments.Record(tx, "reassign", defect, &previous.ID, previousOrganizationID, delegatedFromID, actorID, input.Reason)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось переназначить дефект"})
+		return
+	}
+	audit.Record(h.db, "defects", defect.ID, "UPDATE", actorID,
+		map[string]any{"assignee_id": previous.ID, "organization_id": previousOrganizationID},
+		map[string]any{"assignee_id": assignee.ID, "organization_id": defect.OrganizationID},
+		input.Reason)
+	assignments.NotifyReassigned(h.mailer, defect, previous, assignee, input.Reason)
+
+	h.db.Preload("Assignee", models.WithDeleted).Preload("Organization").First(&defect, defectID)
+	c.JSON(http.StatusOK, gin.H{"defect": defect})
+}
+
+// ListAssignments возвращает историю назначений дефекта.
+func (h *DefectHandler) ListAssignments(c *gin.Context) {
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return
+	}
+
+	var defect models.Defect
+	if err := h.db.First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewDefect(h.db, uint(userID.(float64)), role.(string), defect) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	history, err := assignments.History(h.db, defect.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить историю назначений"})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
 func (h *DefectHandler) LeaderDefectsStats(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -276,45 +681,256 @@ func (h *DefectHandler) LeaderDefectsStats(c *gin.Context) {
 		return
 	}
 
-	var total int64
-	if err := h.db.Model(&models.Defect{}).Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику дефектов"})
+	if _, err := h.filteredDefects(c, h.db); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var new int64
-	if err := h.db.Model(&models.Defect{}).Where("status = ?", "new").Count(&new).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику дефектов"})
-		return
+	// Без фильтров цифры берутся из хранилища статистики; фильтры по меткам, местам и полям
+	// хранилище не различает, поэтому с ними дефекты считаются напрямую.
+	statsFunc := h.storedDefectsStats
+	if len(c.Request.URL.Query()) > 0 {
+		statsFunc = h.liveDefectsStats
 	}
-
-	var closed int64
-	if err := h.db.Model(&models.Defect{}).Where("status = ?", "closed").Count(&closed).Error; err != nil {
+	counts, byLabel, refreshedAt, err := statsFunc(c)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику дефектов"})
 		return
 	}
 
-	var resolved int64
-	if err := h.db.Model(&models.Defect{}).Where("status = ?", "resolved").Count(&resolved).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику дефектов"})
-		return
+	var total int64
+	for _, count := range counts {
+		total += count
 	}
 
-	var in_progress int64
-	if err := h.db.Model(&models.Defect{}).Where("status = ?", "in_progress").Count(&in_progress).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику дефектов"})
+	// Затраты считаются по тем же дефектам, что и цифры по статусам, с учётом фильтров запроса.
+	defectIDs, _ := h.filteredDefects(c, h.db.Model(&models.Defect{}))
+	totals, err := costs.Sum(h.db, defectIDs.Select("defects.id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось посчитать затраты"})
 		return
 	}
 
+	c.Header(stats.FreshnessHeader, stats.FormatFreshness(refreshedAt))
 	c.JSON(http.StatusOK, gin.H{
 		"total_registered": total,
-		"new":              new,
-		"closed":           closed,
-		"resolved":         resolved,
-		"in_progress":      in_progress,
+		"new":              counts["new"],
+		"closed":           counts["closed"],
+		"resolved":         counts["resolved"],
+		"in_progress":      counts["in_progress"],
+		"reopened":         counts["reopened"],
+		"by_label":         byLabel,
+		"costs":            totals,
+		"refreshed_at":     refreshedAt,
 	})
 }
 
+// storedDefectsStats читает статистику руководителя из хранилища.
+func (h *DefectHandler) storedDefectsStats(c *gin.Context) (map[string]int64, []models.LabelStats, *time.Time, error) {
+	refreshedAt, err := stats.Freshness(h.db)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	counts, err := stats.DefectCounts(h.db)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	byLabel, err := stats.LabelCounts(h.db)
+	return counts, byLabel, refreshedAt, err
+}
+
+// liveDefectsStats считает статистику по текущим дефектам с фильтрами запроса.
+func (h *DefectHandler) liveDefectsStats(c *gin.Context) (map[string]int64, []models.LabelStats, *time.Time, error) {
+	now := time.Now()
+	query, err := h.filteredDefects(c, h.db.Model(&models.Defect{}))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var byStatus []struct {
+		Status string
+		Count  int64
+	}
+	if err := query.Select("status, COUNT(*) AS count").Group("status").Scan(&byStatus).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	counts := map[string]int64{}
+	for _, row := range byStatus {
+		counts[row.Status] = row.Count
+	}
+
+	// Метки считаются по тем же отфильтрованным дефектам; метки удалённых проектов не выводятся.
+	defectIDs, _ := h.filteredDefects(c, h.db.Model(&models.Defect{}))
+	var byLabel []models.LabelStats
+	if err := h.db.Table("labels").
+		Select(`labels.id, labels.name, labels.color, labels.kind, labels.project_id,
+			COUNT(defects.id) AS total,
+			COUNT(defects.id) FILTER (WHERE defects.status <> 'closed') AS open,
+			COUNT(defects.id) FILTER (WHERE defects.status = 'closed') AS closed`).
+		Joins("JOIN projects p ON p.id = labels.project_id AND p.deleted_at IS NULL").
+		Joins("LEFT JOIN defect_labels ON defect_labels.label_id = labels.id").
+		Joins("LEFT JOIN defects ON defects.id = defect_labels.defect_id AND defects.id IN (?)", defectIDs.Select("defects.id")).
+		Group("labels.id").
+		Order("total DESC, labels.name").
+		Scan(&byLabel).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	return counts, byLabel, &now, nil
+}
+
+func (h *DefectHandler) ExportDefectsXLSX(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не удалось определить пользователя"})
+		return
+	}
+	role, _ := c.Get("role")
+	currentUserID := uint(userID.(float64))
+
+	if role != "Менеджер" && role != "Инженер" && role != "Исполнитель" && role != "Руководитель" && role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	groupColumn := map[string]string{"project": "defects.project_id", "status": "defects.status"}
+	groupBy := c.DefaultQuery("group_by", "project")
+	if _, ok := groupColumn[groupBy]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Группировка возможна по 'project' или 'status'"})
+		return
+	}
+
+	base := func() *gorm.DB {
+		query := h.db.Model(&models.Defect{})
+		switch role {
+		case "Менеджер":
+			query = query.Where("defects.project_id IN (?)", access.MemberProjects(h.db, currentUserID, "manager"))
+		case "Инженер":
+			query = query.Where("defects.author_id = ? AND defects.project_id IN (?)", currentUserID, access.MemberProjects(h.db, currentUserID))
+		case "Исполнитель":
+			query = query.Where("defects.assignee_id = ? AND defects.project_id IN (?)", currentUserID, access.MemberProjects(h.db, currentUserID))
+		}
+		for _, param := range []string{"project_id", "status", "priority"} {
+			if value := c.Query(param); value != "" {
+				query = query.Where("defects."+param+" = ?", value)
+			}
+		}
+		query, _ = h.filteredDefects(c, query)
+		return query
+	}
+	if _, err := h.filteredDefects(c, h.db); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	type group struct {
+		key   string
+		title string
+	}
+	var groups []group
+	if groupBy == "project" {
+		var projects []models.Project
+		if err := h.db.Where("id IN (?)", base().Distinct("defects.project_id")).Order("name").Find(&projects).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить дефекты"})
+			return
+		}
+		for _, project := range projects {
+			groups = append(groups, group{key: strconv.Itoa(int(project.ID)), title: project.Name})
+		}
+	} else {
+		var present []string
+		if err := base().Distinct("defects.status").Pluck("defects.status", &present).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить дефекты"})
+			return
+		}
+		for _, status := range []string{"new", "in_progress", "resolved", "closed", "reopened"} {
+			if slices.Contains(present, status) {
+				groups = append(groups, group{key: status, title: models.StatusNames[status]})
+			}
+		}
+	}
+
+	register, err := xlsxexport.NewRegister()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать файл"})
+		return
+	}
+
+	for _, g := range groups {
+		var fields []models.CustomField
+		if groupBy == "project" {
+			projectID, _ := strconv.Atoi(g.key)
+			if fields, err = customfields.ProjectFields(h.db, uint(projectID)); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить поля проекта"})
+				return
+			}
+		}
+
+		sheet, err := register.StartSheet(g.title, fields)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать файл"})
+			return
+		}
+
+		var batch []models.Defect
+		err = base().Where(groupColumn[groupBy]+" = ?", g.key).
+			Preload("Project").Preload("Author", models.WithDeleted).Preload("Assignee", models.WithDeleted).Preload("Location").Preload("Labels").Preload("Organization").
+			FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+				ids := make([]uint, 0, len(batch))
+				for _, defect := range batch {
+					ids = append(ids, defect.ID)
+				}
+				batchCosts, err := costs.ForDefects(h.db, ids)
+				if err != nil {
+					return err
+				}
+				for _, defect := range batch {
+					if err := sheet.Add(defect, batchCosts[defect.ID]); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+		if err == nil {
+			err = sheet.Close()
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать файл"})
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("defects_%s.xlsx", time.Now().Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Status(http.StatusOK)
+	if err := register.Write(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+// filteredDefects применяет общие фильтры списков: ?label_id=1,2, ?location_id=3, ?organization_id=4
+// и ?cf.<key>=<value>.
+func (h *DefectHandler) filteredDefects(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	labelIDs, err := utils.ParseIDs(c.Query("label_id"))
+	if err != nil {
+		return nil, err
+	}
+	locationID, err := utils.ParseOptionalID(c.Query("location_id"))
+	if err != nil {
+		return nil, err
+	}
+	organizationID, err := utils.ParseOptionalID(c.Query("organization_id"))
+	if err != nil {
+		return nil, err
+	}
+	if organizationID != nil {
+		query = query.Where("defects.organization_id = ?", *organizationID)
+	}
+
+	query = locations.FilterDefects(labels.FilterDefects(query, labelIDs), locationID)
+	return customfields.FilterDefects(query, c.Request.URL.Query())
+}
+
 func (h *DefectHandler) AttachmentsDownload(c *gin.Context) {
 	filename := c.Param("filename")
 
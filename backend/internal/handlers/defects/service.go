@@ -14,10 +14,16 @@ func (h *DefectHandler) RegisterRoutes(router *gin.Engine) {
 		defect.GET("/yours/assignee", utils.AuthMiddleware(), h.AssigneeListDefects)
 		defect.GET("/download/:filename", h.AttachmentsDownload)
 		defect.GET("/stats", utils.AuthMiddleware(), h.LeaderDefectsStats)
+		defect.GET("/export/xlsx", utils.AuthMiddleware(), h.ExportDefectsXLSX)
+		defect.GET("/assignments/:id", utils.AuthMiddleware(), h.ListAssignments)
 
 		defect.POST("/add", utils.AuthMiddleware(), h.AddDefect)
+		defect.POST("/similar", utils.AuthMiddleware(), h.SimilarDefects)
+		defect.POST("/reassign/:id", utils.AuthMiddleware(), h.ReassignDefect)
 
 		defect.PUT("/edit/engineer/:id", utils.AuthMiddleware(), h.EngineerEditDefect)
 		defect.PUT("/edit/manager/:id", utils.AuthMiddleware(), h.ManagerEditDefect)
+
+		defect.DELETE("/:id", utils.AuthMiddleware(), h.DeleteDefect)
 	}
 }
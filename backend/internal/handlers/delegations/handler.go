@@ -0,0 +1,150 @@
+package delegations
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/audit"
+	"systemacontrolya/internal/delegations"
+	"systemacontrolya/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DelegationsHandler struct {
+	db *gorm.DB
+}
+
+func NewDelegationsHandler(db *gorm.DB) *DelegationsHandler {
+	return &DelegationsHandler{db: db}
+}
+
+// ListDelegations возвращает замещения текущего пользователя: его отпуска и тех, кого замещает он.
+// Администратор с ?user_id видит замещения любого пользователя.
+func (h *DelegationsHandler) ListDelegations(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	ownerID := uint(userID.(float64))
+	if queryID := c.Query("user_id"); queryID != "" && role == "Админ" {
+		id, err := strconv.Atoi(queryID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID пользователя"})
+			return
+		}
+		ownerID = uint(id)
+	}
+
+	query := h.db.Preload("User", models.WithDeleted).Preload("Delegate", models.WithDeleted).
+		Where("user_id = ? OR delegate_id = ?", ownerID, ownerID)
+	if c.Query("active") == "true" {
+		today := time.Now().Format("2006-01-02")
+		query = query.Where("ends_on >= ?", today)
+	}
+
+	var list []models.Delegation
+	if err := query.Order("starts_on DESC, id DESC").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить замещения"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AddDelegation оформляет замещение на время отсутствия. Пользователь оформляет его себе сам,
+// администратор — любому пользователю через user_id.
+func (h *DelegationsHandler) AddDelegation(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	actorID := uint(userID.(float64))
+
+	var input models.DelegationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+	ownerID := actorID
+	if input.UserID != nil && *input.UserID != actorID {
+		if role != "Админ" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Замещение за другого пользователя оформляет администратор"})
+			return
+		}
+		ownerID = *input.UserID
+	}
+
+	startsOn, err := time.Parse("2006-01-02", input.StartsOn)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"})
+		return
+	}
+	endsOn, err := time.Parse("2006-01-02", input.EndsOn)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"})
+		return
+	}
+	if endsOn.Before(time.Now().Truncate(24 * time.Hour)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Замещение не может закончиться в прошлом"})
+		return
+	}
+
+	delegation := models.Delegation{
+		StartsOn:    startsOn,
+		EndsOn:      endsOn,
+		Comment:     input.Comment,
+		UserID:      ownerID,
+		DelegateID:  input.DelegateID,
+		CreatedByID: &actorID,
+	}
+	if err := delegations.Validate(h.db, delegation); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.Omit("User", "Delegate", "CreatedBy").Create(&delegation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить замещение"})
+		return
+	}
+	audit.Record(h.db, "delegations", delegation.ID, "INSERT", actorID, nil, snapshot(delegation), "")
+
+	c.JSON(http.StatusCreated, delegation)
+}
+
+// DeleteDelegation отменяет замещение: это делает замещаемый пользователь или администратор.
+// Уже переданные заместителю дефекты остаются за ним.
+func (h *DelegationsHandler) DeleteDelegation(c *gin.Context) {
+	delegationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID замещения"})
+		return
+	}
+
+	var delegation models.Delegation
+	if err := h.db.First(&delegation, delegationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Замещение не найдено"})
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	actorID := uint(userID.(float64))
+	if delegation.UserID != actorID && role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	if err := h.db.Delete(&delegation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	audit.Record(h.db, "delegations", delegation.ID, "DELETE", actorID, snapshot(delegation), nil, "")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Замещение отменено"})
+}
+
+func snapshot(delegation models.Delegation) map[string]any {
+	return map[string]any{
+		"user_id":     delegation.UserID,
+		"delegate_id": delegation.DelegateID,
+		"starts_on":   delegation.StartsOn.Format("2006-01-02"),
+		"ends_on":     delegation.EndsOn.Format("2006-01-02"),
+		"comment":     delegation.Comment,
+	}
+}
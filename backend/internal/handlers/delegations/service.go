@@ -0,0 +1,18 @@
+package delegations
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *DelegationsHandler) RegisterRoutes(router *gin.Engine) {
+	delegation := router.Group("api/delegations")
+	{
+		delegation.GET("", utils.AuthMiddleware(), h.ListDelegations)
+
+		delegation.POST("", utils.AuthMiddleware(), h.AddDelegation)
+
+		delegation.DELETE("/:id", utils.AuthMiddleware(), h.DeleteDelegation)
+	}
+}
@@ -0,0 +1,260 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/imports"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/stats"
+	"systemacontrolya/internal/trash"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxFileSize = 10 << 20
+
+var errDefectsInWork = errors.New("defects in work")
+
+type ImportsHandler struct {
+	db *gorm.DB
+}
+
+func NewImportsHandler(db *gorm.DB) *ImportsHandler {
+	return &ImportsHandler{db: db}
+}
+
+// ImportDefects загружает дефекты из CSV или XLSX. Без confirm=true выполняется пробный прогон:
+// возвращаются сопоставление колонок, ошибки по строкам и предпросмотр, в базу ничего не пишется.
+// Подтверждённый импорт выполняется одной транзакцией и только если ни в одной строке нет ошибок.
+func (h *ImportsHandler) ImportDefects(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не удалось определить пользователя"})
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "Инженер" && role != "Менеджер" && role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не передан"})
+		return
+	}
+	if fileHeader.Size > maxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл больше 10 МБ"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось открыть файл"})
+		return
+	}
+	defer file.Close()
+
+	table, err := imports.Read(fileHeader.Filename, file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mapping, err := imports.DetectMapping(table.Header, c.PostForm("mapping"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if missing := mapping.Missing(); len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Не найдены колонки для обязательных полей",
+			"missing":  missing,
+			"columns":  table.Header,
+			"mapping":  mapping.Describe(table.Header),
+			"unmapped": mapping.Unmapped(table.Header),
+		})
+		return
+	}
+
+	checker, err := imports.NewChecker(h.db, uint(userID.(float64)), role.(string), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось проверить файл"})
+		return
+	}
+	rows := checker.Check(table, mapping)
+
+	invalid := 0
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			invalid++
+		}
+	}
+	report := gin.H{
+		"columns":  table.Header,
+		"mapping":  mapping.Describe(table.Header),
+		"unmapped": mapping.Unmapped(table.Header),
+		"total":    len(rows),
+		"valid":    len(rows) - invalid,
+		"invalid":  invalid,
+		"rows":     rows,
+	}
+
+	if c.PostForm("confirm") != "true" {
+		report["dry_run"] = true
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	if invalid > 0 {
+		report["error"] = "В файле есть строки с ошибками, импорт не выполнен"
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	job := models.ImportJob{
+		Filename: fileHeader.Filename,
+		Status:   "applied",
+		Total:    len(rows),
+		UserID:   uint(userID.(float64)),
+	}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&job).Error; err != nil {
+			return err
+		}
+		defects := make([]models.Defect, 0, len(rows))
+		for _, row := range rows {
+			defects = append(defects, checker.Defect(row.Preview, job.ID))
+		}
+		return tx.CreateInBatches(&defects, 200).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка импорта дефектов"})
+		return
+	}
+
+	report["job"] = job
+	c.JSON(http.StatusCreated, report)
+}
+
+func (h *ImportsHandler) ListJobs(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	query := h.db.Preload("User", models.WithDeleted).Order("created_at DESC")
+	if role != "Админ" {
+		query = query.Where("user_id = ?", uint(userID.(float64)))
+	}
+
+	var jobs []models.ImportJob
+	if err := query.Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить историю импорта"})
+		return
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+func (h *ImportsHandler) GetJob(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+
+	var defects []models.Defect
+	if err := h.db.Preload("Project").Preload("Assignee", models.WithDeleted).Where("import_job_id = ?", job.ID).Order("id").Find(&defects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить дефекты импорта"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job, "defects": defects})
+}
+
+// RollbackJob перемещает дефекты импорта в корзину. Откат невозможен, если по дефектам уже началась работа:
+// появились отчёты или статус ушёл дальше «в работе».
+func (h *ImportsHandler) RollbackJob(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+	if job.Status == "rolled_back" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Импорт уже откачен"})
+		return
+	}
+
+	var removed, touched int64
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Defect{}).
+			Where("import_job_id = ?", job.ID).
+			Where("status NOT IN ? OR EXISTS (SELECT 1 FROM reports WHERE reports.defect_id = defects.id)", []string{"new", "in_progress"}).
+			Count(&touched).Error; err != nil {
+			return err
+		}
+		if touched > 0 {
+			return errDefectsInWork
+		}
+
+		var defects []models.Defect
+		if err := tx.Where("import_job_id = ?", job.ID).Find(&defects).Error; err != nil {
+			return err
+		}
+
+		// Дефекты уходят в корзину, как при обычном удалении: на них уже могут ссылаться трудозатраты,
+		// материалы, связи и метки, а из корзины их можно восстановить.
+		now := time.Now()
+		projects := map[uint]bool{}
+		var projectIDs []uint
+		for _, defect := range defects {
+			if err := trash.DeleteDefect(tx, defect, now); err != nil {
+				return err
+			}
+			if !projects[defect.ProjectID] {
+				projects[defect.ProjectID] = true
+				projectIDs = append(projectIDs, defect.ProjectID)
+			}
+		}
+		removed = int64(len(defects))
+		if err := stats.Touch(tx, projectIDs...); err != nil {
+			return err
+		}
+
+		job.Status = "rolled_back"
+		job.RolledBackAt = &now
+		return tx.Model(&job).Select("status", "rolled_back_at").Updates(&job).Error
+	})
+	if errors.Is(err, errDefectsInWork) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Импорт нельзя откатить: по %d дефектам уже идёт работа", touched)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось откатить импорт"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job, "removed": removed})
+}
+
+// ownJob находит импорт по :id; смотреть и откатывать его может автор импорта или администратор.
+func (h *ImportsHandler) ownJob(c *gin.Context) (models.ImportJob, bool) {
+	var job models.ImportJob
+	jobID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID импорта"})
+		return job, false
+	}
+	if err := h.db.Preload("User", models.WithDeleted).First(&job, jobID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Импорт не найден"})
+		return job, false
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if role != "Админ" && job.UserID != uint(userID.(float64)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return job, false
+	}
+	return job, true
+}
@@ -0,0 +1,18 @@
+package imports
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *ImportsHandler) RegisterRoutes(router *gin.Engine) {
+	imports := router.Group("api/imports")
+	{
+		imports.GET("/jobs", utils.AuthMiddleware(), h.ListJobs)
+		imports.GET("/jobs/:id", utils.AuthMiddleware(), h.GetJob)
+
+		imports.POST("/defects", utils.AuthMiddleware(), h.ImportDefects)
+		imports.POST("/jobs/:id/rollback", utils.AuthMiddleware(), h.RollbackJob)
+	}
+}
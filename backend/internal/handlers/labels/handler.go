@@ -0,0 +1,220 @@
+package labels
+
+import (
+	"net/http"
+	"strconv"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/labels"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/stats"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LabelsHandler struct {
+	db *gorm.DB
+}
+
+func NewLabelsHandler(db *gorm.DB) *LabelsHandler {
+	return &LabelsHandler{db: db}
+}
+
+func (h *LabelsHandler) ListLabels(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, uint(projectID), uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	var labels []models.Label
+	query := h.db.Where("project_id = ?", projectID)
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if err := query.Order("kind, name").Find(&labels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить метки"})
+		return
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+func (h *LabelsHandler) AddLabel(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if !access.IsProjectManager(h.db, project.ID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Метками управляет менеджер проекта"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, project.ID) {
+		return
+	}
+
+	var input models.LabelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	label := models.Label{
+		Name:      input.Name,
+		Color:     input.Color,
+		Kind:      input.Kind,
+		ProjectID: project.ID,
+	}
+	if label.Color == "" {
+		label.Color = "#9ca3af"
+	}
+	if label.Kind == "" {
+		label.Kind = "other"
+	}
+
+	if err := h.db.Create(&label).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Метка с таким названием уже есть в проекте"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, label)
+}
+
+func (h *LabelsHandler) EditLabel(c *gin.Context) {
+	label, ok := h.managedLabel(c)
+	if !ok {
+		return
+	}
+
+	var input models.LabelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	label.Name = input.Name
+	if input.Color != "" {
+		label.Color = input.Color
+	}
+	if input.Kind != "" {
+		label.Kind = input.Kind
+	}
+
+	if err := h.db.Save(&label).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Метка с таким названием уже есть в проекте"})
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+func (h *LabelsHandler) DeleteLabel(c *gin.Context) {
+	label, ok := h.managedLabel(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM defect_labels WHERE label_id = ?", label.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&label).Error; err != nil {
+			return err
+		}
+		return stats.Touch(tx, label.ProjectID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Метка удалена"})
+}
+
+func (h *LabelsHandler) SetDefectLabels(c *gin.Context) {
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return
+	}
+
+	var defect models.Defect
+	if err := h.db.Preload("Project").First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	currentUserID := uint(userID.(float64))
+	if !access.IsProjectManager(h.db, defect.ProjectID, currentUserID) && defect.AuthorID != currentUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
+
+	var input models.DefectLabelsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	projectLabels, err := labels.ProjectLabels(h.db, defect.ProjectID, input.LabelIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Model(&defect).Association("Labels").Replace(projectLabels); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить метки дефекта"})
+		return
+	}
+	stats.Touch(h.db, defect.ProjectID)
+
+	h.db.Preload("Labels").First(&defect, defect.ID)
+	c.JSON(http.StatusOK, gin.H{"defect": defect})
+}
+
+func (h *LabelsHandler) managedLabel(c *gin.Context) (models.Label, bool) {
+	var label models.Label
+
+	labelID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID метки"})
+		return label, false
+	}
+
+	if err := h.db.Preload("Project").First(&label, labelID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Метка не найдена"})
+		return label, false
+	}
+
+	userID, _ := c.Get("userID")
+	if !access.IsProjectManager(h.db, label.ProjectID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Метками управляет менеджер проекта"})
+		return label, false
+	}
+	if !access.RequireActiveProject(c, h.db, label.ProjectID) {
+		return label, false
+	}
+
+	return label, true
+}
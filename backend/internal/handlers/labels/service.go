@@ -0,0 +1,21 @@
+package labels
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *LabelsHandler) RegisterRoutes(router *gin.Engine) {
+	labels := router.Group("api/labels")
+	{
+		labels.GET("/project/:project_id", utils.AuthMiddleware(), h.ListLabels)
+
+		labels.POST("/project/:project_id", utils.AuthMiddleware(), h.AddLabel)
+
+		labels.PUT("/edit/:id", utils.AuthMiddleware(), h.EditLabel)
+		labels.PUT("/defect/:id", utils.AuthMiddleware(), h.SetDefectLabels)
+
+		labels.DELETE("/delete/:id", utils.AuthMiddleware(), h.DeleteLabel)
+	}
+}
@@ -0,0 +1,134 @@
+package links
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/links"
+	"systemacontrolya/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LinksHandler struct {
+	db *gorm.DB
+}
+
+func NewLinksHandler(db *gorm.DB) *LinksHandler {
+	return &LinksHandler{db: db}
+}
+
+// ListDefectLinks возвращает связи дефекта в обе стороны и открытые блокирующие дефекты.
+func (h *LinksHandler) ListDefectLinks(c *gin.Context) {
+	defect, ok := h.findDefect(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewDefect(h.db, uint(userID.(float64)), role.(string), defect) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	var list []models.DefectLink
+	if err := h.db.Preload("Source").Preload("Target").
+		Where("source_id = ? OR target_id = ?", defect.ID, defect.ID).
+		Order("id").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить связи"})
+		return
+	}
+	blockers, err := links.OpenBlockers(h.db, defect.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить связи"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"links": list, "open_blockers": blockers})
+}
+
+// AddDefectLink связывает дефект :id с другим дефектом проекта. Связывать может менеджер или инженер проекта.
+func (h *LinksHandler) AddDefectLink(c *gin.Context) {
+	defect, ok := h.findDefect(c)
+	if !ok {
+		return
+	}
+	actorID, ok := h.checkEdit(c, defect.ProjectID)
+	if !ok {
+		return
+	}
+
+	var input models.DefectLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	link, err := links.Add(h.db, defect, input, actorID, time.Now())
+	if errors.Is(err, links.ErrCycle) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Связь образует цикл"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.db.Preload("Source").Preload("Target").First(&link, link.ID)
+	c.JSON(http.StatusCreated, link)
+}
+
+func (h *LinksHandler) DeleteDefectLink(c *gin.Context) {
+	linkID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID связи"})
+		return
+	}
+
+	var link models.DefectLink
+	if err := h.db.Preload("Source").First(&link, linkID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Связь не найдена"})
+		return
+	}
+	actorID, ok := h.checkEdit(c, link.Source.ProjectID)
+	if !ok {
+		return
+	}
+
+	if err := links.Remove(h.db, link, actorID, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Связь удалена"})
+}
+
+func (h *LinksHandler) findDefect(c *gin.Context) (models.Defect, bool) {
+	var defect models.Defect
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return defect, false
+	}
+	if err := h.db.First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return defect, false
+	}
+	return defect, true
+}
+
+func (h *LinksHandler) checkEdit(c *gin.Context, projectID uint) (uint, bool) {
+	userID, _ := c.Get("userID")
+	actorID := uint(userID.(float64))
+	if !access.HasProjectRole(h.db, projectID, actorID, "manager", "engineer") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Связями управляют менеджер и инженеры проекта"})
+		return 0, false
+	}
+	if !access.RequireActiveProject(c, h.db, projectID) {
+		return 0, false
+	}
+	return actorID, true
+}
@@ -0,0 +1,18 @@
+package links
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *LinksHandler) RegisterRoutes(router *gin.Engine) {
+	links := router.Group("api/links")
+	{
+		links.GET("/defect/:id", utils.AuthMiddleware(), h.ListDefectLinks)
+
+		links.POST("/defect/:id", utils.AuthMiddleware(), h.AddDefectLink)
+
+		links.DELETE("/delete/:id", utils.AuthMiddleware(), h.DeleteDefectLink)
+	}
+}
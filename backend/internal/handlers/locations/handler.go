@@ -0,0 +1,292 @@
+package locations
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/locations"
+	"systemacontrolya/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LocationsHandler struct {
+	db *gorm.DB
+}
+
+func NewLocationsHandler(db *gorm.DB) *LocationsHandler {
+	return &LocationsHandler{db: db}
+}
+
+func (h *LocationsHandler) ListLocations(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var flat []models.Location
+	if err := h.db.Where("project_id = ?", projectID).Order("path").Find(&flat).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить места проекта"})
+		return
+	}
+
+	c.JSON(http.StatusOK, locations.Tree(flat))
+}
+
+func (h *LocationsHandler) AddLocation(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if !access.IsProjectManager(h.db, project.ID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Местами управляет менеджер проекта"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, project.ID) {
+		return
+	}
+
+	var input models.LocationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	parent, err := locations.ProjectLocation(h.db, project.ID, input.ParentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if parent != nil && !locations.CanContain(parent.Kind, input.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нарушен порядок вложенности: площадка → здание → этаж → помещение"})
+		return
+	}
+
+	location := models.Location{
+		ProjectID: project.ID,
+		ParentID:  input.ParentID,
+		Name:      input.Name,
+		Kind:      input.Kind,
+		Path:      "/",
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&location).Error; err != nil {
+			return err
+		}
+		location.Path = locations.ChildPath(parent, location.ID)
+		return tx.Model(&location).Update("path", location.Path).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать место"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, location)
+}
+
+func (h *LocationsHandler) EditLocation(c *gin.Context) {
+	location, ok := h.managedLocation(c)
+	if !ok {
+		return
+	}
+
+	var input models.EditLocationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	if input.Kind != location.Kind {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Тип места изменить нельзя"})
+		return
+	}
+
+	parentID := location.ParentID
+	if len(input.ParentID) > 0 {
+		parentID = nil
+		if err := json.Unmarshal(input.ParentID, &parentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID родительского места"})
+			return
+		}
+	}
+
+	parent, err := locations.ProjectLocation(h.db, location.ProjectID, parentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if parent != nil && !locations.CanContain(parent.Kind, location.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нарушен порядок вложенности: площадка → здание → этаж → помещение"})
+		return
+	}
+
+	location.Name = input.Name
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := locations.Move(tx, &location, parent); err != nil {
+			return err
+		}
+		return tx.Model(&location).Select("name", "parent_id", "path").Updates(&location).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+func (h *LocationsHandler) DeleteLocation(c *gin.Context) {
+	location, ok := h.managedLocation(c)
+	if !ok {
+		return
+	}
+
+	var children int64
+	if err := h.db.Model(&models.Location{}).Where("parent_id = ?", location.ID).Count(&children).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки связей"})
+		return
+	}
+	if children > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Сначала удалите вложенные места"})
+		return
+	}
+
+	var defects int64
+	if err := h.db.Model(&models.Defect{}).Where("location_id = ?", location.ID).Count(&defects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки связей"})
+		return
+	}
+	if defects > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "К месту привязаны дефекты"})
+		return
+	}
+
+	if err := h.db.Delete(&location).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Место удалено"})
+}
+
+func (h *LocationsHandler) SetDefectLocation(c *gin.Context) {
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return
+	}
+
+	var defect models.Defect
+	if err := h.db.Preload("Project").First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	currentUserID := uint(userID.(float64))
+	if !access.IsProjectManager(h.db, defect.ProjectID, currentUserID) && defect.AuthorID != currentUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
+
+	var input models.DefectLocationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	if _, err := locations.ProjectLocation(h.db, defect.ProjectID, input.LocationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Model(&defect).Update("location_id", input.LocationID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить место дефекта"})
+		return
+	}
+
+	h.db.Preload("Location").First(&defect, defect.ID)
+	c.JSON(http.StatusOK, gin.H{"defect": defect})
+}
+
+func (h *LocationsHandler) LocationStats(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, uint(projectID), uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	var stats []models.LocationStats
+	if err := h.db.Raw(`
+		SELECT
+			l.id,
+			l.name,
+			l.kind,
+			l.parent_id,
+			COUNT(d.id) AS total,
+			COUNT(d.id) FILTER (WHERE d.status <> 'closed') AS open,
+			COUNT(d.id) FILTER (WHERE d.status <> 'closed' AND d.priority = 'critical') AS open_critical
+		FROM locations l
+		LEFT JOIN locations sub ON sub.path LIKE l.path || '%'
+		LEFT JOIN defects d ON d.location_id = sub.id AND d.deleted_at IS NULL
+		WHERE l.project_id = ? AND l.kind IN ('building', 'floor')
+		GROUP BY l.id
+		ORDER BY l.path
+	`, projectID).Scan(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику по местам"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *LocationsHandler) managedLocation(c *gin.Context) (models.Location, bool) {
+	var location models.Location
+
+	locationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID места"})
+		return location, false
+	}
+
+	if err := h.db.Preload("Project").First(&location, locationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Место не найдено"})
+		return location, false
+	}
+
+	userID, _ := c.Get("userID")
+	if !access.IsProjectManager(h.db, location.ProjectID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Местами управляет менеджер проекта"})
+		return location, false
+	}
+	if !access.RequireActiveProject(c, h.db, location.ProjectID) {
+		return location, false
+	}
+
+	return location, true
+}
@@ -0,0 +1,22 @@
+package locations
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *LocationsHandler) RegisterRoutes(router *gin.Engine) {
+	locations := router.Group("api/locations")
+	{
+		locations.GET("/project/:project_id", utils.AuthMiddleware(), h.ListLocations)
+		locations.GET("/project/:project_id/stats", utils.AuthMiddleware(), h.LocationStats)
+
+		locations.POST("/project/:project_id", utils.AuthMiddleware(), h.AddLocation)
+
+		locations.PUT("/edit/:id", utils.AuthMiddleware(), h.EditLocation)
+		locations.PUT("/defect/:id", utils.AuthMiddleware(), h.SetDefectLocation)
+
+		locations.DELETE("/delete/:id", utils.AuthMiddleware(), h.DeleteLocation)
+	}
+}
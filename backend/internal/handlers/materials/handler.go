@@ -0,0 +1,251 @@
+package materials
+
+import (
+	"net/http"
+	"strconv"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/costs"
+	"systemacontrolya/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MaterialsHandler struct {
+	db *gorm.DB
+}
+
+func NewMaterialsHandler(db *gorm.DB) *MaterialsHandler {
+	return &MaterialsHandler{db: db}
+}
+
+// ListMaterials возвращает справочник материалов проекта.
+func (h *MaterialsHandler) ListMaterials(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, uint(projectID), uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	var list []models.Material
+	if err := h.db.Where("project_id = ?", projectID).Order("name").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить материалы"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *MaterialsHandler) AddMaterial(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+	userID, _ := c.Get("userID")
+	if !access.IsProjectManager(h.db, project.ID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Справочником материалов управляет менеджер проекта"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, project.ID) {
+		return
+	}
+
+	var input models.MaterialInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	material := models.Material{Name: input.Name, Unit: input.Unit, UnitPrice: input.UnitPrice, ProjectID: project.ID}
+	if err := h.db.Create(&material).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Материал с таким названием уже есть в проекте"})
+		return
+	}
+	c.JSON(http.StatusCreated, material)
+}
+
+// EditMaterial меняет позицию справочника. Новая цена действует для следующих списаний.
+func (h *MaterialsHandler) EditMaterial(c *gin.Context) {
+	material, ok := h.managedMaterial(c)
+	if !ok {
+		return
+	}
+
+	var input models.MaterialInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	material.Name = input.Name
+	material.Unit = input.Unit
+	material.UnitPrice = input.UnitPrice
+	if err := h.db.Save(&material).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Материал с таким названием уже есть в проекте"})
+		return
+	}
+	c.JSON(http.StatusOK, material)
+}
+
+// DeleteMaterial удаляет позицию справочника, если она ещё не списывалась.
+func (h *MaterialsHandler) DeleteMaterial(c *gin.Context) {
+	material, ok := h.managedMaterial(c)
+	if !ok {
+		return
+	}
+
+	var used int64
+	if err := h.db.Model(&models.DefectMaterial{}).Where("material_id = ?", material.ID).Count(&used).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	if used > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Материал уже списывался на дефекты, удалить его нельзя"})
+		return
+	}
+
+	if err := h.db.Delete(&material).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Материал удалён"})
+}
+
+// ListDefectMaterials возвращает расход материалов по дефекту и итоговые затраты на него.
+func (h *MaterialsHandler) ListDefectMaterials(c *gin.Context) {
+	defect, ok := h.findDefect(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewDefect(h.db, uint(userID.(float64)), role.(string), defect) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	var usage []models.DefectMaterial
+	if err := h.db.Preload("Material").Where("defect_id = ?", defect.ID).Order("id").Find(&usage).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить материалы"})
+		return
+	}
+	totals, err := costs.ForDefects(h.db, []uint{defect.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось посчитать затраты"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"materials": usage, "costs": totals[defect.ID]})
+}
+
+// AddDefectMaterial списывает материал на дефект: это делает назначенный исполнитель или менеджер проекта.
+func (h *MaterialsHandler) AddDefectMaterial(c *gin.Context) {
+	defect, ok := h.findDefect(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+	currentUserID := uint(userID.(float64))
+	isAssignee := defect.AssigneeID != nil && *defect.AssigneeID == currentUserID
+	if !isAssignee && !access.IsProjectManager(h.db, defect.ProjectID, currentUserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Материалы списывает исполнитель или менеджер проекта"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
+
+	var input models.DefectMaterialInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	usage, err := costs.AddMaterial(h.db, defect, input, currentUserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, usage)
+}
+
+// DeleteDefectMaterial отменяет списание; отменить может автор списания или менеджер проекта.
+func (h *MaterialsHandler) DeleteDefectMaterial(c *gin.Context) {
+	usageID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID списания"})
+		return
+	}
+
+	var usage models.DefectMaterial
+	if err := h.db.Preload("Defect").First(&usage, usageID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Списание не найдено"})
+		return
+	}
+	userID, _ := c.Get("userID")
+	currentUserID := uint(userID.(float64))
+	isAuthor := usage.CreatedByID != nil && *usage.CreatedByID == currentUserID
+	if !isAuthor && !access.IsProjectManager(h.db, usage.Defect.ProjectID, currentUserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, usage.Defect.ProjectID) {
+		return
+	}
+
+	if err := h.db.Delete(&usage).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Списание отменено"})
+}
+
+func (h *MaterialsHandler) findDefect(c *gin.Context) (models.Defect, bool) {
+	var defect models.Defect
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return defect, false
+	}
+	if err := h.db.First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return defect, false
+	}
+	return defect, true
+}
+
+func (h *MaterialsHandler) managedMaterial(c *gin.Context) (models.Material, bool) {
+	var material models.Material
+	materialID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID материала"})
+		return material, false
+	}
+	if err := h.db.First(&material, materialID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Материал не найден"})
+		return material, false
+	}
+
+	userID, _ := c.Get("userID")
+	if !access.IsProjectManager(h.db, material.ProjectID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Справочником материалов управляет менеджер проекта"})
+		return material, false
+	}
+	if !access.RequireActiveProject(c, h.db, material.ProjectID) {
+		return material, false
+	}
+	return material, true
+}
@@ -0,0 +1,26 @@
+package materials
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *MaterialsHandler) RegisterRoutes(router *gin.Engine) {
+	materials := router.Group("api/materials")
+	{
+		materials.GET("/project/:id", utils.AuthMiddleware(), h.ListMaterials)
+
+		materials.POST("/project/:id", utils.AuthMiddleware(), h.AddMaterial)
+
+		materials.PUT("/edit/:id", utils.AuthMiddleware(), h.EditMaterial)
+
+		materials.DELETE("/delete/:id", utils.AuthMiddleware(), h.DeleteMaterial)
+
+		materials.GET("/defect/:id", utils.AuthMiddleware(), h.ListDefectMaterials)
+
+		materials.POST("/defect/:id", utils.AuthMiddleware(), h.AddDefectMaterial)
+
+		materials.DELETE("/usage/:id", utils.AuthMiddleware(), h.DeleteDefectMaterial)
+	}
+}
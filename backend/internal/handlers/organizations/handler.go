@@ -0,0 +1,282 @@
+package organizations
+
+import (
+	"net/http"
+	"strconv"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/audit"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/organizations"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OrganizationsHandler struct {
+	db *gorm.DB
+}
+
+func NewOrganizationsHandler(db *gorm.DB) *OrganizationsHandler {
+	return &OrganizationsHandler{db: db}
+}
+
+func (h *OrganizationsHandler) ListOrganizations(c *gin.Context) {
+	var list []models.Organization
+	if err := h.db.Order("name").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить организации"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *OrganizationsHandler) AddOrganization(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+
+	var organization models.Organization
+	if !h.bindOrganization(c, &organization) {
+		return
+	}
+	if err := h.db.Create(&organization).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Организация с таким названием уже существует"})
+		return
+	}
+	audit.Record(h.db, "organizations", organization.ID, "INSERT", actorID, nil, organizations.Snapshot(organization), "")
+
+	c.JSON(http.StatusCreated, organization)
+}
+
+func (h *OrganizationsHandler) EditOrganization(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	organization, ok := h.findOrganization(c)
+	if !ok {
+		return
+	}
+
+	before := organization
+	if !h.bindOrganization(c, &organization) {
+		return
+	}
+	if err := h.db.Save(&organization).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Организация с таким названием уже существует"})
+		return
+	}
+	audit.Record(h.db, "organizations", organization.ID, "UPDATE", actorID, organizations.Snapshot(before), organizations.Snapshot(organization), "")
+
+	c.JSON(http.StatusOK, organization)
+}
+
+// DeleteOrganization удаляет организацию, на которую не ссылаются пользователи, проекты и дефекты.
+func (h *OrganizationsHandler) DeleteOrganization(c *gin.Context) {
+	actorID, ok := h.adminID(c)
+	if !ok {
+		return
+	}
+	organization, ok := h.findOrganization(c)
+	if !ok {
+		return
+	}
+
+	reason, err := organizations.InUse(h.db, organization.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	if reason != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Нельзя удалить организацию: " + reason})
+		return
+	}
+
+	if err := h.db.Delete(&organization).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	audit.Record(h.db, "organizations", organization.ID, "DELETE", actorID, organizations.Snapshot(organization), nil, "")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Организация удалена"})
+}
+
+// ListUsers возвращает сотрудников организации; с ?project_id — только участников проекта.
+func (h *OrganizationsHandler) ListUsers(c *gin.Context) {
+	role, _ := c.Get("role")
+	if role != "Админ" && role != "Руководитель" && role != "Менеджер" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	organization, ok := h.findOrganization(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Preload("Role").Where("organization_id = ?", organization.ID)
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("id IN (?)", h.db.Model(&models.ProjectMember{}).Select("user_id").Where("project_id = ?", projectID))
+	}
+
+	var list []models.User
+	if err := query.Order("last_name, first_name").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить сотрудников"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ListProjectOrganizations возвращает организации, привлечённые к проекту.
+func (h *OrganizationsHandler) ListProjectOrganizations(c *gin.Context) {
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, project.ID, uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	var list []models.ProjectOrganization
+	if err := h.db.Preload("Organization").Where("project_id = ?", project.ID).Order("created_at").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить организации"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AddProjectOrganization привлекает организацию к проекту: это делает администратор или менеджер проекта.
+func (h *OrganizationsHandler) AddProjectOrganization(c *gin.Context) {
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+	actorID, ok := h.projectEditor(c, project)
+	if !ok {
+		return
+	}
+
+	var input models.ProjectOrganizationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+	if err := organizations.Exists(h.db, &input.OrganizationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if organizations.Contracted(h.db, project.ID, input.OrganizationID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Организация уже привлечена к проекту"})
+		return
+	}
+
+	link := models.ProjectOrganization{ProjectID: project.ID, OrganizationID: input.OrganizationID}
+	if err := h.db.Omit("Organization").Create(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось привлечь организацию"})
+		return
+	}
+	audit.Record(h.db, "project_organizations", project.ID, "INSERT", actorID, nil, gin.H{"project_id": project.ID, "organization_id": input.OrganizationID}, "")
+
+	h.db.Preload("Organization").Where("project_id = ? AND organization_id = ?", project.ID, input.OrganizationID).First(&link)
+	c.JSON(http.StatusCreated, link)
+}
+
+// RemoveProjectOrganization снимает организацию с проекта, если за ней нет незакрытых дефектов.
+func (h *OrganizationsHandler) RemoveProjectOrganization(c *gin.Context) {
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+	actorID, ok := h.projectEditor(c, project)
+	if !ok {
+		return
+	}
+	organizationID, err := strconv.Atoi(c.Param("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID организации"})
+		return
+	}
+
+	if err := organizations.Withdraw(h.db, project.ID, uint(organizationID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	audit.Record(h.db, "project_organizations", project.ID, "DELETE", actorID, gin.H{"project_id": project.ID, "organization_id": organizationID}, nil, "")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Организация снята с проекта"})
+}
+
+func (h *OrganizationsHandler) bindOrganization(c *gin.Context, organization *models.Organization) bool {
+	var input models.OrganizationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return false
+	}
+
+	organization.Name = input.Name
+	organization.TaxID = input.TaxID
+	organization.Contact = input.Contact
+	if input.Kind != "" {
+		organization.Kind = input.Kind
+	}
+	if organization.Kind == "" {
+		organization.Kind = "subcontractor"
+	}
+	return true
+}
+
+func (h *OrganizationsHandler) findOrganization(c *gin.Context) (models.Organization, bool) {
+	var organization models.Organization
+	organizationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID организации"})
+		return organization, false
+	}
+	if err := h.db.First(&organization, organizationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Организация не найдена"})
+		return organization, false
+	}
+	return organization, true
+}
+
+func (h *OrganizationsHandler) findProject(c *gin.Context) (models.Project, bool) {
+	var project models.Project
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return project, false
+	}
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return project, false
+	}
+	return project, true
+}
+
+// projectEditor пропускает администратора и менеджера проекта; архивный проект не меняется.
+func (h *OrganizationsHandler) projectEditor(c *gin.Context, project models.Project) (uint, bool) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	actorID := uint(userID.(float64))
+	if role != "Админ" && !access.IsProjectManager(h.db, project.ID, actorID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return 0, false
+	}
+	if !access.RequireActiveProject(c, h.db, project.ID) {
+		return 0, false
+	}
+	return actorID, true
+}
+
+func (h *OrganizationsHandler) adminID(c *gin.Context) (uint, bool) {
+	role, _ := c.Get("role")
+	userID, exists := c.Get("userID")
+	if !exists || role != "Админ" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return 0, false
+	}
+	return uint(userID.(float64)), true
+}
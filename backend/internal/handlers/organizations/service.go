@@ -0,0 +1,24 @@
+package organizations
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *OrganizationsHandler) RegisterRoutes(router *gin.Engine) {
+	organizations := router.Group("api/organizations")
+	{
+		organizations.GET("", utils.AuthMiddleware(), h.ListOrganizations)
+		organizations.GET("/:id/users", utils.AuthMiddleware(), h.ListUsers)
+		organizations.GET("/project/:id", utils.AuthMiddleware(), h.ListProjectOrganizations)
+
+		organizations.POST("", utils.AuthMiddleware(), h.AddOrganization)
+		organizations.POST("/project/:id", utils.AuthMiddleware(), h.AddProjectOrganization)
+
+		organizations.PUT("/:id", utils.AuthMiddleware(), h.EditOrganization)
+
+		organizations.DELETE("/:id", utils.AuthMiddleware(), h.DeleteOrganization)
+		organizations.DELETE("/project/:id/:organization_id", utils.AuthMiddleware(), h.RemoveProjectOrganization)
+	}
+}
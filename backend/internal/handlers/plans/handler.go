@@ -0,0 +1,365 @@
+package plans
+
+import (
+	"fmt"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/locations"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/plans"
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const plansDir = "uploads/plans"
+
+type PlansHandler struct {
+	db *gorm.DB
+}
+
+func NewPlansHandler(db *gorm.DB) *PlansHandler {
+	return &PlansHandler{db: db}
+}
+
+func (h *PlansHandler) ListPlans(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, uint(projectID), uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	query := h.db.Where("project_id = ?", projectID)
+	if locationID := c.Query("location_id"); locationID != "" {
+		query = query.Where("location_id = ?", locationID)
+	}
+
+	var floorPlans []models.FloorPlan
+	if err := query.Order("name").Find(&floorPlans).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить планы"})
+		return
+	}
+
+	c.JSON(http.StatusOK, floorPlans)
+}
+
+func (h *PlansHandler) UploadPlan(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	managerID := uint(userID.(float64))
+	if !access.IsProjectManager(h.db, project.ID, managerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Планы загружает менеджер проекта"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, project.ID) {
+		return
+	}
+
+	name := c.PostForm("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Название плана обязательно"})
+		return
+	}
+
+	locationID, err := utils.ParseOptionalID(c.PostForm("location_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID места"})
+		return
+	}
+	if _, err := locations.ProjectLocation(h.db, project.ID, locationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл плана не передан"})
+		return
+	}
+
+	mimeType := file.Header.Get("Content-Type")
+	if !plans.ImageTypes[mimeType] && mimeType != "application/pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "План должен быть в формате PNG, JPEG или PDF"})
+		return
+	}
+
+	if err := os.MkdirAll(plansDir, 0o755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить файл"})
+		return
+	}
+
+	filePath, err := savePlanFile(c, file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить файл"})
+		return
+	}
+
+	plan := models.FloorPlan{
+		Name:         name,
+		FilePath:     "/" + filePath,
+		MimeType:     mimeType,
+		ProjectID:    project.ID,
+		LocationID:   locationID,
+		UploadedByID: managerID,
+	}
+
+	// Для PDF отметки рисуются на растровом превью, которое можно приложить к плану.
+	imagePath := filePath
+	if !plans.ImageTypes[mimeType] {
+		imagePath = ""
+		if preview, err := c.FormFile("preview"); err == nil {
+			if !plans.ImageTypes[preview.Header.Get("Content-Type")] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Превью должно быть в формате PNG или JPEG"})
+				return
+			}
+			imagePath, err = savePlanFile(c, preview)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить файл"})
+				return
+			}
+			plan.PreviewPath = "/" + imagePath
+		}
+	}
+
+	if imagePath != "" {
+		cfg, err := plans.DecodeConfig(imagePath)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать изображение плана"})
+			return
+		}
+		plan.Width = cfg.Width
+		plan.Height = cfg.Height
+	}
+
+	if err := h.db.Create(&plan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить план"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+func (h *PlansHandler) DeletePlan(c *gin.Context) {
+	planID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID плана"})
+		return
+	}
+
+	var plan models.FloorPlan
+	if err := h.db.Preload("Project").First(&plan, planID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "План не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if !access.IsProjectManager(h.db, plan.ProjectID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, plan.ProjectID) {
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Defect{}).Where("plan_id = ?", plan.ID).
+			Updates(map[string]any{"plan_id": nil, "pin_x": nil, "pin_y": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&plan).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "План удалён"})
+}
+
+func (h *PlansHandler) ListPins(c *gin.Context) {
+	plan, ok := h.findPlan(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, plan.ProjectID, uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	pins, err := h.planPins(plan, uint(userID.(float64)), role.(string), c.Query("open") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить отметки"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plan": plan, "pins": pins})
+}
+
+func (h *PlansHandler) SetDefectPin(c *gin.Context) {
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return
+	}
+
+	var defect models.Defect
+	if err := h.db.Preload("Project").First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	currentUserID := uint(userID.(float64))
+	if !access.IsProjectManager(h.db, defect.ProjectID, currentUserID) && defect.AuthorID != currentUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
+
+	var input models.DefectPinInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Координаты должны быть в диапазоне от 0 до 1"})
+		return
+	}
+
+	if input.PlanID != nil {
+		if input.X == nil || input.Y == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите координаты отметки"})
+			return
+		}
+		var count int64
+		if err := h.db.Model(&models.FloorPlan{}).Where("id = ? AND project_id = ?", *input.PlanID, defect.ProjectID).Count(&count).Error; err != nil || count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "План не найден в проекте дефекта"})
+			return
+		}
+	} else {
+		input.X, input.Y = nil, nil
+	}
+
+	if err := h.db.Model(&defect).Updates(map[string]any{
+		"plan_id": input.PlanID,
+		"pin_x":   input.X,
+		"pin_y":   input.Y,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить отметку"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"defect_id": defect.ID, "plan_id": input.PlanID, "x": input.X, "y": input.Y})
+}
+
+func (h *PlansHandler) ExportPlan(c *gin.Context) {
+	plan, ok := h.findPlan(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, plan.ProjectID, uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	imagePath := plan.FilePath
+	if !plans.ImageTypes[plan.MimeType] {
+		imagePath = plan.PreviewPath
+	}
+	if imagePath == "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Для PDF-плана не загружено превью, экспорт с отметками недоступен"})
+		return
+	}
+
+	background, err := plans.DecodeFile("." + imagePath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pins, err := h.planPins(plan, uint(userID.(float64)), role.(string), c.Query("all") != "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить отметки"})
+		return
+	}
+
+	filename := fmt.Sprintf("plan_%d.png", plan.ID)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Content-Type", "image/png")
+	c.Status(http.StatusOK)
+	if err := png.Encode(c.Writer, plans.Annotate(background, pins)); err != nil {
+		c.Error(err)
+	}
+}
+
+func (h *PlansHandler) findPlan(c *gin.Context) (models.FloorPlan, bool) {
+	var plan models.FloorPlan
+
+	planID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID плана"})
+		return plan, false
+	}
+
+	if err := h.db.First(&plan, planID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "План не найден"})
+		return plan, false
+	}
+
+	return plan, true
+}
+
+// planPins возвращает отметки дефектов на плане. Кому дефекты проекта видны не все, тот получает
+// только отметки дефектов, которые он создал или которые назначены ему.
+func (h *PlansHandler) planPins(plan models.FloorPlan, userID uint, role string, onlyOpen bool) ([]models.PlanPin, error) {
+	query := h.db.Model(&models.Defect{}).
+		Select("id AS defect_id, title, status, priority, pin_x AS x, pin_y AS y").
+		Where("plan_id = ? AND pin_x IS NOT NULL AND pin_y IS NOT NULL", plan.ID)
+	if !access.CanViewDefect(h.db, userID, role, models.Defect{ProjectID: plan.ProjectID}) {
+		query = query.Where("author_id = ? OR assignee_id = ?", userID, userID)
+	}
+	if onlyOpen {
+		query = query.Where("status <> ?", "closed")
+	}
+
+	var pins []models.PlanPin
+	err := query.Order("id").Scan(&pins).Error
+	return pins, err
+}
+
+func savePlanFile(c *gin.Context, file *multipart.FileHeader) (string, error) {
+	savePath := filepath.ToSlash(filepath.Join(plansDir, fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(file.Filename))))
+	if err := c.SaveUploadedFile(file, savePath); err != nil {
+		return "", err
+	}
+	return savePath, nil
+}
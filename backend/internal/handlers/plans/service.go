@@ -0,0 +1,22 @@
+package plans
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *PlansHandler) RegisterRoutes(router *gin.Engine) {
+	plans := router.Group("api/plans")
+	{
+		plans.GET("/project/:project_id", utils.AuthMiddleware(), h.ListPlans)
+		plans.GET("/pins/:id", utils.AuthMiddleware(), h.ListPins)
+		plans.GET("/export/:id", utils.AuthMiddleware(), h.ExportPlan)
+
+		plans.POST("/project/:project_id", utils.AuthMiddleware(), h.UploadPlan)
+
+		plans.PUT("/pin/defect/:id", utils.AuthMiddleware(), h.SetDefectPin)
+
+		plans.DELETE("/delete/:id", utils.AuthMiddleware(), h.DeletePlan)
+	}
+}
@@ -2,7 +2,10 @@ package projects
 
 import (
 	"net/http"
+	"strconv"
+	"systemacontrolya/internal/access"
 	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/stats"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -24,35 +27,54 @@ func (h *ProjectsHandler) ManagerViewProject(c *gin.Context) {
 	}
 	managerID := uint(userID.(float64))
 
-	var summaries []models.ProjectSummary
-
-	err := h.db.Raw(`
-		SELECT 
-			p.id,
-			p.name,
-			p.description,
-			COUNT(d.id) AS defects_count,
-			COUNT(DISTINCT d.author_id) AS engineers_count,
-			COUNT(DISTINCT d.assignee_id) AS assignees_count
-		FROM projects p
-		LEFT JOIN defects d ON d.project_id = p.id
-		WHERE p.manager_id = ?
-		GROUP BY p.id
-	`, managerID).Scan(&summaries).Error
-
+	refreshedAt, err := stats.Freshness(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить проекты"})
+		return
+	}
+	summaries, err := stats.ProjectSummaries(h.db, access.MemberProjects(h.db, managerID, "manager"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить проекты"})
 		return
 	}
 
+	c.Header(stats.FreshnessHeader, stats.FormatFreshness(refreshedAt))
 	c.JSON(http.StatusOK, summaries)
 }
 
+// ListProjects возвращает проекты, в которых состоит пользователь; админ и руководитель видят все.
 func (h *ProjectsHandler) ListProjects(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
 	var projects []models.Project
-	if err := h.db.Find(&projects).Error; err != nil {
+	query := access.ScopeProjects(h.db, h.db, "id", uint(userID.(float64)), role.(string))
+	if err := query.Find(&projects).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки проектов"})
 		return
 	}
 	c.JSON(http.StatusOK, projects)
 }
+
+func (h *ProjectsHandler) ListMembers(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, uint(projectID), uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	var members []models.ProjectMember
+	if err := h.db.Preload("User").Where("project_id = ?", projectID).Order("role, id").Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить участников проекта"})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
@@ -11,5 +11,6 @@ func (h *ProjectsHandler) RegisterRoutes(router *gin.Engine) {
 	{
 		project.GET("/yours/manager", utils.AuthMiddleware(), h.ManagerViewProject)
 		project.GET("/all", utils.AuthMiddleware(), h.ListProjects)
+		project.GET("/:id/members", utils.AuthMiddleware(), h.ListMembers)
 	}
 }
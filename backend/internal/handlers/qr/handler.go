@@ -0,0 +1,277 @@
+package qr
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/locations"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/pdfdoc"
+	"systemacontrolya/internal/qrcode"
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-pdf/fpdf"
+	"gorm.io/gorm"
+)
+
+// codeAlphabet не содержит похожих символов (0/O, 1/I/L), чтобы код можно было ввести вручную.
+const codeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+const maxSheetDefects = 240
+
+type QRHandler struct {
+	db *gorm.DB
+}
+
+func NewQRHandler(db *gorm.DB) *QRHandler {
+	return &QRHandler{db: db}
+}
+
+func (h *QRHandler) DefectQR(c *gin.Context) {
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return
+	}
+
+	var defect models.Defect
+	if err := h.db.First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewDefect(h.db, uint(userID.(float64)), role.(string), defect) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	code, err := h.ensureCode(&defect)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать код дефекта"})
+		return
+	}
+
+	link := deepLink(code)
+	switch c.DefaultQuery("format", "png") {
+	case "svg":
+		svg, err := qrcode.SVG(link)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать QR-код"})
+			return
+		}
+		c.Data(http.StatusOK, "image/svg+xml", svg)
+	case "png":
+		size, err := strconv.Atoi(c.DefaultQuery("size", "256"))
+		if err != nil || size < 64 || size > 2048 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Размер должен быть от 64 до 2048 пикселей"})
+			return
+		}
+		image, err := qrcode.PNG(link, size)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать QR-код"})
+			return
+		}
+		c.Data(http.StatusOK, "image/png", image)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Поддерживаются форматы png и svg"})
+	}
+}
+
+func (h *QRHandler) LabelsSheet(c *gin.Context) {
+	var input models.QRSheetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+	if len(input.DefectIDs) == 0 && input.LocationID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите дефекты или место"})
+		return
+	}
+
+	query := h.db.Preload("Project").Preload("Location").Order("defects.id")
+	if len(input.DefectIDs) > 0 {
+		query = query.Where("defects.id IN ?", input.DefectIDs)
+	}
+	query = locations.FilterDefects(query, input.LocationID)
+
+	var defects []models.Defect
+	if err := query.Limit(maxSheetDefects + 1).Find(&defects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить дефекты"})
+		return
+	}
+	if len(defects) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефекты не найдены"})
+		return
+	}
+	if len(defects) > maxSheetDefects {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("За один раз можно напечатать не более %d этикеток", maxSheetDefects)})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	for _, defect := range defects {
+		if !access.CanViewDefect(h.db, uint(userID.(float64)), role.(string), defect) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Нет доступа к дефекту #%d", defect.ID)})
+			return
+		}
+	}
+
+	pdf := pdfdoc.New("P")
+	for i := range defects {
+		if _, err := h.ensureCode(&defects[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать код дефекта"})
+			return
+		}
+	}
+	if err := renderLabels(pdf, defects); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать PDF"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать PDF"})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=defect_labels.pdf")
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+func (h *QRHandler) Resolve(c *gin.Context) {
+	code := strings.ToUpper(strings.TrimSpace(c.Param("code")))
+
+	var defect models.Defect
+	if err := h.db.Where("public_code = ?", code).
+		Preload("Project").Preload("Author", models.WithDeleted).Preload("Assignee", models.WithDeleted).Preload("Location").
+		First(&defect).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Код не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewDefect(h.db, uint(userID.(float64)), role.(string), defect) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "У вас нет доступа к этому дефекту"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"defect": defect})
+}
+
+// ensureCode выдаёт дефекту постоянный код для QR-ссылки при первом обращении.
+func (h *QRHandler) ensureCode(defect *models.Defect) (string, error) {
+	if defect.PublicCode != nil {
+		return *defect.PublicCode, nil
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := randomCode(10)
+		if err != nil {
+			return "", err
+		}
+		result := h.db.Model(&models.Defect{}).
+			Where("id = ? AND public_code IS NULL", defect.ID).
+			Update("public_code", code)
+		if result.Error != nil {
+			continue
+		}
+		if result.RowsAffected == 0 {
+			// Код успел выдать параллельный запрос.
+			if err := h.db.Select("public_code").First(defect, defect.ID).Error; err != nil {
+				return "", err
+			}
+			return *defect.PublicCode, nil
+		}
+		defect.PublicCode = &code
+		return code, nil
+	}
+	return "", fmt.Errorf("не удалось подобрать уникальный код")
+}
+
+func randomCode(length int) (string, error) {
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = codeAlphabet[int(b)%len(codeAlphabet)]
+	}
+	return string(buf), nil
+}
+
+func deepLink(code string) string {
+	return utils.AppURL() + "/scan/" + code
+}
+
+// renderLabels раскладывает этикетки 70×37 мм по 24 на лист A4 (3 колонки × 8 рядов).
+func renderLabels(pdf *fpdf.Fpdf, defects []models.Defect) error {
+	const (
+		cols       = 3
+		rows       = 8
+		labelW     = 70.0
+		labelH     = 37.0
+		marginTop  = 0.5
+		qrSize     = 30.0
+		padding    = 3.5
+		textOffset = padding + qrSize + 2
+	)
+
+	pdf.SetAutoPageBreak(false, 0)
+	for i, defect := range defects {
+		if i%(cols*rows) == 0 {
+			pdf.AddPage()
+		}
+		slot := i % (cols * rows)
+		x := float64(slot%cols) * labelW
+		y := marginTop + float64(slot/cols)*labelH
+
+		link := deepLink(*defect.PublicCode)
+		image, err := qrcode.PNG(link, 240)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("qr_%d", defect.ID)
+		pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(image))
+		pdf.ImageOptions(name, x+padding, y+padding, qrSize, qrSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, link)
+
+		textWidth := labelW - textOffset - padding
+		pdf.SetXY(x+textOffset, y+padding+1)
+		pdf.SetFont(pdfdoc.Font, "B", 11)
+		pdf.CellFormat(textWidth, 5, fmt.Sprintf("#%d", defect.ID), "", 2, "L", false, 0, "")
+
+		pdf.SetFont(pdfdoc.Font, "", 7)
+		for _, line := range firstLines(pdf, defect.Title, textWidth, 3) {
+			pdf.CellFormat(textWidth, 3.5, line, "", 2, "L", false, 0, "")
+		}
+		pdf.CellFormat(textWidth, 3.5, firstLines(pdf, defect.Project.Name, textWidth, 1)[0], "", 2, "L", false, 0, "")
+		if defect.Location != nil {
+			pdf.CellFormat(textWidth, 3.5, firstLines(pdf, defect.Location.Name, textWidth, 1)[0], "", 2, "L", false, 0, "")
+		}
+		pdf.SetFont(pdfdoc.Font, "B", 8)
+		pdf.CellFormat(textWidth, 4, *defect.PublicCode, "", 2, "L", false, 0, "")
+	}
+
+	return pdf.Error()
+}
+
+func firstLines(pdf *fpdf.Fpdf, text string, width float64, count int) []string {
+	lines := pdf.SplitText(text, width)
+	if len(lines) == 0 {
+		return []string{""}
+	}
+	if len(lines) > count {
+		lines = lines[:count]
+		lines[count-1] = strings.TrimRight(lines[count-1], " ") + "…"
+	}
+	return lines
+}
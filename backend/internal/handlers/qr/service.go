@@ -0,0 +1,17 @@
+package qr
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *QRHandler) RegisterRoutes(router *gin.Engine) {
+	qr := router.Group("api/qr")
+	{
+		qr.GET("/defect/:id", utils.AuthMiddleware(), h.DefectQR)
+		qr.GET("/resolve/:code", utils.AuthMiddleware(), h.Resolve)
+
+		qr.POST("/sheet", utils.AuthMiddleware(), h.LabelsSheet)
+	}
+}
@@ -4,11 +4,20 @@ import (
 	"bytes"
 	"encoding/csv"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/calendar"
+	"systemacontrolya/internal/customfields"
+	"systemacontrolya/internal/delegations"
+	"systemacontrolya/internal/links"
 	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/pdfdoc"
+	"systemacontrolya/internal/sla"
+	"systemacontrolya/internal/stats"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -28,7 +37,7 @@ func (h *ReportsHandler) BossListReports(c *gin.Context) {
 	if err := h.db.
 		Joins("JOIN defects on defects.id = reports.defect_id").
 		Where("defects.status = ? AND reports.status = ?", "closed", "approve").
-		Preload("User").Preload("Project").
+		Preload("User", models.WithDeleted).Preload("Project").
 		Find(&reports).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Все отчеты не найдены"})
 		return
@@ -53,10 +62,9 @@ func (h *ReportsHandler) ManagerListReports(c *gin.Context) {
 	}
 
 	if err := h.db.
-		Joins("JOIN projects ON projects.id = reports.project_id").
 		Joins("JOIN defects on defects.id = reports.defect_id").
 		Preload("Project").
-		Where("projects.manager_id = ? AND defects.status = ? AND reports.status = ?", managerID, "resolved", "approve").
+		Where("reports.project_id IN (?) AND defects.status = ? AND reports.status = ?", access.MemberProjects(h.db, managerID, "manager"), "resolved", "approve").
 		Find(&reports).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Отчёты менеджера не найдены"})
 		return
@@ -82,26 +90,38 @@ func (h *ReportsHandler) ExportReportCSV(c *gin.Context) {
 	id := c.Param("id")
 
 	var report models.Report
-	if err := h.db.Preload("User").Preload("Project").First(&report, id).Error; err != nil {
+	if err := h.db.Preload("User", models.WithDeleted).Preload("Project").Preload("Defect").First(&report, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Отчёт не найден"})
 		return
 	}
 
+	fields, err := customfields.ProjectFields(h.db, report.ProjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить поля проекта"})
+		return
+	}
+
 	b := &bytes.Buffer{}
 	b.Write([]byte{0xEF, 0xBB, 0xBF})
 
 	writer := csv.NewWriter(b)
 	writer.Comma = ';'
 
-	writer.Write([]string{"Название", "Описание", "Дата создания", "Автор", "Проект"})
-
-	writer.Write([]string{
+	header := []string{"Название", "Описание", "Дата создания", "Автор", "Проект"}
+	row := []string{
 		report.Title,
 		report.Description,
 		report.CreatedAt.Format("2006-01-02 15:04"),
 		fmt.Sprintf("%s %s", report.User.LastName, report.User.FirstName),
 		report.Project.Name,
-	})
+	}
+	for _, field := range fields {
+		header = append(header, field.Name)
+		row = append(row, customfields.Display(report.Defect.CustomFields[field.Key]))
+	}
+
+	writer.Write(header)
+	writer.Write(row)
 
 	writer.Flush()
 
@@ -110,6 +130,120 @@ func (h *ReportsHandler) ExportReportCSV(c *gin.Context) {
 	c.Data(http.StatusOK, "text/csv; charset=utf-8", b.Bytes())
 }
 
+func (h *ReportsHandler) ExportReportPDF(c *gin.Context) {
+	reportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID отчета"})
+		return
+	}
+
+	var report models.Report
+	if err := h.db.Preload("User", models.WithDeleted).Preload("Project.Manager", models.WithDeleted).First(&report, reportID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Отчёт не найден"})
+		return
+	}
+
+	var defect models.Defect
+	if err := h.db.Preload("Author", models.WithDeleted).Preload("Assignee", models.WithDeleted).Preload("Location").Preload("Labels").
+		First(&defect, report.DefectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewDefect(h.db, uint(userID.(float64)), role.(string), defect) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	var reviews []models.ReportReview
+	if err := h.db.Preload("Reviewer", models.WithDeleted).Where("report_id = ?", report.ID).Order("created_at").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить решения по отчёту"})
+		return
+	}
+
+	pdf := pdfdoc.AcceptanceCertificate(pdfdoc.AcceptanceData{
+		Report:  report,
+		Defect:  defect,
+		Manager: report.Project.Manager,
+		Reviews: reviews,
+	})
+
+	var b bytes.Buffer
+	if err := pdf.Output(&b); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать PDF"})
+		return
+	}
+
+	filename := fmt.Sprintf("act_%d.pdf", report.ID)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "application/pdf", b.Bytes())
+}
+
+func (h *ReportsHandler) ExportProjectPDF(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.Preload("Manager", models.WithDeleted).First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if role != "Руководитель" && role != "Админ" && !access.HasProjectRole(h.db, project.ID, uint(userID.(float64)), "manager", "observer") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, -1, 0)
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse("2006-01-02", raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты 'from'"})
+			return
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse("2006-01-02", raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты 'to'"})
+			return
+		}
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Дата окончания периода раньше даты начала"})
+		return
+	}
+
+	var defects []models.Defect
+	if err := h.db.Preload("Assignee", models.WithDeleted).Preload("Location").
+		Where("project_id = ? AND status = ? AND closed_at >= ? AND closed_at < ?", project.ID, "closed", from, to).
+		Order("closed_at").
+		Find(&defects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить дефекты"})
+		return
+	}
+
+	pdf := pdfdoc.ClosedDefectsSummary(project, defects, from, to)
+
+	var b bytes.Buffer
+	if err := pdf.Output(&b); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать PDF"})
+		return
+	}
+
+	filename := fmt.Sprintf("project_%d_closed.pdf", project.ID)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "application/pdf", b.Bytes())
+}
+
 func (h *ReportsHandler) AssigneeAddReport(c *gin.Context) {
 	defectID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -138,6 +272,13 @@ func (h *ReportsHandler) AssigneeAddReport(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не назначены на этот дефект"})
 		return
 	}
+	if !access.HasProjectRole(h.db, defect.ProjectID, uint(userID.(float64)), "assignee") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не входите в команду проекта"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
 
 	if defect.Status != "in_progress" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Дефект должен быть в статусе 'in_progress'"})
@@ -150,6 +291,15 @@ func (h *ReportsHandler) AssigneeAddReport(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Название и описание отчета обязательны"})
 		return
 	}
+	var labourCost *float64
+	if value := c.PostForm("labour_cost"); value != "" {
+		cost, err := strconv.ParseFloat(value, 64)
+		if err != nil || cost < 0 || math.IsNaN(cost) || math.IsInf(cost, 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверная стоимость работ"})
+			return
+		}
+		labourCost = &cost
+	}
 
 	form, err := c.MultipartForm()
 	if err != nil {
@@ -173,24 +323,29 @@ func (h *ReportsHandler) AssigneeAddReport(c *gin.Context) {
 		Description: description,
 		FilePaths:   paths,
 		Status:      "pending",
+		LabourCost:  labourCost,
 		ProjectID:   defect.ProjectID,
 		UserID:      uint(userID.(float64)),
 		DefectID:    uint(defectID),
 		CreatedAt:   time.Now(),
 	}
 
-	if err := h.db.Create(&report).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать отчёт"})
-		return
-	}
-
 	defect.Status = "in_progress"
-	if err := h.db.Save(&defect).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить дефект"})
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&defect).Error; err != nil {
+			return err
+		}
+		return links.StatusChanged(tx, defect.ID, uint(userID.(float64)), time.Now())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать отчёт"})
 		return
 	}
 
-	h.db.Preload("Project").Preload("Author").Preload("Assignee").First(&defect, defectID)
+	h.db.Preload("Project").Preload("Author", models.WithDeleted).Preload("Assignee", models.WithDeleted).First(&defect, defectID)
 	c.JSON(http.StatusCreated, gin.H{
 		"report": report,
 		"defect": defect,
@@ -223,11 +378,13 @@ func (h *ReportsHandler) ManagerReviewReport(c *gin.Context) {
 		return
 	}
 
-	var project models.Project
-	if err := h.db.First(&project, defect.ProjectID).Error; err != nil || project.ManagerID != managerID {
+	if !h.managesProject(defect.ProjectID, managerID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
 		return
 	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
 
 	if defect.Status != "resolved" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Дефект должен быть в статусе 'resolved'"})
@@ -236,6 +393,7 @@ func (h *ReportsHandler) ManagerReviewReport(c *gin.Context) {
 
 	var input struct {
 		Decision string `json:"decision" binding:"required"`
+		Comment  string `json:"comment"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
@@ -247,45 +405,43 @@ func (h *ReportsHandler) ManagerReviewReport(c *gin.Context) {
 		return
 	}
 
-	isOverdue := defect.DueDate != nil && time.Now().After(*defect.DueDate)
+	now := time.Now()
+	isOverdue := calendar.ForProject(h.db, defect.ProjectID).IsOverdue(defect.DueDate, now)
 
+	previousStatus := defect.Status
 	switch input.Decision {
 	case "approve":
 		if isOverdue {
-			newDue := time.Now().Add(72 * time.Hour)
+			newDue := sla.ExtensionDue(h.db, &defect, now)
 			defect.Status = "in_progress"
 			defect.DueDate = &newDue
-			if err := h.db.Save(&defect).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить срок выполнения"})
-				return
-			}
+			sla.StampStatus(&defect, previousStatus, now)
 		} else {
-			defect.Status = "closed"
-			if err := h.db.Save(&defect).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось закрыть дефект"})
+			if err := links.CheckClose(h.db, defect.ID); err != nil {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 				return
 			}
+			defect.Status = "closed"
+			sla.StampStatus(&defect, previousStatus, now)
 		}
 
 	case "reject":
-		newDue := time.Now().Add(72 * time.Hour)
+		newDue := sla.ExtensionDue(h.db, &defect, now)
 		defect.Status = "in_progress"
 		report.Status = "reject"
 		defect.DueDate = &newDue
-		if err := h.db.Save(&defect).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить дефект после отклонения"})
-			return
-		}
-		if err := h.db.Save(&report).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить отчет после отклонения"})
-			return
-		}
+		sla.StampStatus(&defect, previousStatus, now)
 
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректное значение decision. Используйте 'approve' или 'reject'"})
 		return
 	}
 
+	if err := h.saveReview(&report, &defect, managerID, "manager", input.Decision, input.Comment, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить решение по отчёту"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"report": report,
 		"defect": defect,
@@ -303,10 +459,9 @@ func (h *ReportsHandler) ManagerPendingReports(c *gin.Context) {
 	var reports []models.Report
 	if err := h.db.
 		Joins("JOIN defects ON defects.id = reports.defect_id").
-		Joins("JOIN projects ON projects.id = reports.project_id").
-		Where("reports.status = ? AND defects.status = ? AND projects.manager_id = ?", "approve", "resolved", managerID).
+		Where("reports.status = ? AND defects.status = ? AND reports.project_id IN (?)", "approve", "resolved", h.managedProjects(managerID)).
 		Preload("Project").
-		Preload("User").
+		Preload("User", models.WithDeleted).
 		Preload("Defect").
 		Find(&reports).Error; err != nil {
 
@@ -342,10 +497,16 @@ func (h *ReportsHandler) EngineerReviewReport(c *gin.Context) {
 		return
 	}
 
-	if defect.AuthorID != uint(userID.(float64)) {
+	// Пока автор дефекта в отпуске, отчёт проверяет его заместитель.
+	reviewerID := uint(userID.(float64))
+	isReviewer := defect.AuthorID == reviewerID || delegations.ActsFor(h.db, reviewerID, defect.AuthorID, time.Now())
+	if !isReviewer || !access.HasProjectRole(h.db, defect.ProjectID, defect.AuthorID, "engineer", "manager") {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не назначены инженером для этого дефекта"})
 		return
 	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
 
 	if defect.Status != "in_progress" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Дефект должен быть в статусе 'in_progress'"})
@@ -354,6 +515,7 @@ func (h *ReportsHandler) EngineerReviewReport(c *gin.Context) {
 
 	var input struct {
 		Decision string `json:"decision" binding:"required,oneof=approve reject"`
+		Comment  string `json:"comment"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
@@ -362,29 +524,62 @@ func (h *ReportsHandler) EngineerReviewReport(c *gin.Context) {
 
 	report.Status = input.Decision
 
+	// Отклонённый отчёт остаётся в истории дефекта со статусом reject, исполнитель присылает новый.
+	previousStatus := defect.Status
 	if input.Decision == "reject" {
 		defect.Status = "in_progress"
-		if err := h.db.Delete(&report, reportID).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось удалить дефект"})
-			return
-		}
 	} else {
 		defect.Status = "resolved"
 	}
+	now := time.Now()
+	sla.StampStatus(&defect, previousStatus, now)
 
-	if err := h.db.Save(&report).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить отчёт"})
-		return
-	}
-
-	if err := h.db.Save(&defect).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить дефект"})
+	if err := h.saveReview(&report, &defect, reviewerID, "engineer", input.Decision, input.Comment, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить решение по отчёту"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"report": report, "defect": defect})
 }
 
+// saveReview одной транзакцией сохраняет отчёт и дефект, решение проверяющего и пересчитанный
+// статус родительского дефекта.
+func (h *ReportsHandler) saveReview(report *models.Report, defect *models.Defect, reviewerID uint, stage, decision, comment string, now time.Time) error {
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(report).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(defect).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.ReportReview{
+			ReportID:   report.ID,
+			ReviewerID: reviewerID,
+			Stage:      stage,
+			Decision:   decision,
+			Comment:    comment,
+		}).Error; err != nil {
+			return err
+		}
+		return links.StatusChanged(tx, defect.ID, reviewerID, now)
+	})
+}
+
+// managedProjects — подзапрос проектов, где пользователь менеджер сам или замещает менеджера.
+func (h *ReportsHandler) managedProjects(userID uint) *gorm.DB {
+	return h.db.Model(&models.ProjectMember{}).
+		Select("project_id").
+		Where("role = ? AND user_id IN (?)", "manager", delegations.Principals(h.db, userID, time.Now()))
+}
+
+func (h *ReportsHandler) managesProject(projectID, userID uint) bool {
+	var count int64
+	if err := h.managedProjects(userID).Where("project_id = ?", projectID).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
 func (h *ReportsHandler) EngineerPendingReports(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -395,8 +590,11 @@ func (h *ReportsHandler) EngineerPendingReports(c *gin.Context) {
 	engineerID := uint(userID.(float64))
 
 	var reports []models.Report
-	if err := h.db.Where("status = ? AND defect_id IN (SELECT id FROM defects WHERE author_id = ?)", "pending", engineerID).
-		Preload("Defect").Preload("Project").Preload("User").Find(&reports).Error; err != nil {
+	// Кроме своих дефектов инженер видит отчёты по дефектам тех, кого сейчас замещает.
+	if err := h.db.Where("status = ?", "pending").
+		Where("defect_id IN (SELECT d.id FROM defects d JOIN project_members pm ON pm.project_id = d.project_id AND pm.user_id = d.author_id WHERE d.author_id IN (?))",
+			delegations.Principals(h.db, engineerID, time.Now())).
+		Preload("Defect").Preload("Project").Preload("User", models.WithDeleted).Find(&reports).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения отчётов"})
 		return
 	}
@@ -411,24 +609,28 @@ func (h *ReportsHandler) LeaderReportsStats(c *gin.Context) {
 		return
 	}
 
-	type DailyReports struct {
-		Date  time.Time `json:"date"`
-		Count int       `json:"count"`
+	// days задаёт глубину графика, по умолчанию неделя.
+	days := 7
+	if raw := c.Query("days"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > 366 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Параметр days должен быть от 1 до 366"})
+			return
+		}
+		days = value
 	}
 
-	var stats []DailyReports
-
-	if err := h.db.
-		Table("reports").
-		Select("DATE(created_at) AS date, COUNT(*) AS count").
-		Where("created_at >= CURRENT_DATE - INTERVAL '7 days'").
-		Group("DATE(created_at)").
-		Order("DATE(created_at)").
-		Scan(&stats).Error; err != nil {
-
+	refreshedAt, err := stats.Freshness(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику отчетов"})
+		return
+	}
+	daily, err := stats.DailyReports(h.db, days)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику отчетов"})
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	c.Header(stats.FreshnessHeader, stats.FormatFreshness(refreshedAt))
+	c.JSON(http.StatusOK, daily)
 }
@@ -14,6 +14,8 @@ func (h *ReportsHandler) RegisterRoutes(router *gin.Engine) {
 		report.GET("/yours/manager/pending", utils.AuthMiddleware(), h.ManagerPendingReports)
 		report.GET("/yours/engineer/pending", utils.AuthMiddleware(), h.EngineerPendingReports)
 		report.GET("/export/:id/csv", h.ExportReportCSV)
+		report.GET("/export/:id/pdf", utils.AuthMiddleware(), h.ExportReportPDF)
+		report.GET("/export/project/:project_id/pdf", utils.AuthMiddleware(), h.ExportProjectPDF)
 		report.GET("/download/:filename", utils.AuthMiddleware(), h.ReportFileDownload)
 		report.GET("/stats", utils.AuthMiddleware(), h.LeaderReportsStats)
 
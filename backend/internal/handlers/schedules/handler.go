@@ -0,0 +1,205 @@
+package schedules
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/mailer"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/schedules"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SchedulesHandler struct {
+	db     *gorm.DB
+	mailer mailer.Sender
+}
+
+func NewSchedulesHandler(db *gorm.DB) *SchedulesHandler {
+	return &SchedulesHandler{db: db, mailer: mailer.FromEnv()}
+}
+
+// ListSchedules возвращает расписания сводок текущего руководителя.
+func (h *SchedulesHandler) ListSchedules(c *gin.Context) {
+	leaderID, ok := h.leaderID(c)
+	if !ok {
+		return
+	}
+
+	var list []models.ReportSchedule
+	if err := h.db.Preload("Project").Where("user_id = ?", leaderID).Order("id").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить расписания"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateSchedule создаёт расписание; первая сводка уйдёт на ближайшей границе периода.
+func (h *SchedulesHandler) CreateSchedule(c *gin.Context) {
+	leaderID, ok := h.leaderID(c)
+	if !ok {
+		return
+	}
+
+	schedule := models.ReportSchedule{UserID: leaderID, Enabled: true}
+	if !h.bindSchedule(c, &schedule) {
+		return
+	}
+	schedule.NextRunAt = schedules.Next(schedule.Frequency, time.Now())
+
+	if err := h.db.Create(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать расписание"})
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// UpdateSchedule меняет расписание; при смене периодичности срок следующей сводки пересчитывается.
+func (h *SchedulesHandler) UpdateSchedule(c *gin.Context) {
+	schedule, ok := h.ownSchedule(c)
+	if !ok {
+		return
+	}
+
+	frequency := schedule.Frequency
+	if !h.bindSchedule(c, &schedule) {
+		return
+	}
+	if schedule.Frequency != frequency {
+		schedule.NextRunAt = schedules.Next(schedule.Frequency, time.Now())
+	}
+
+	if err := h.db.Select("name", "frequency", "format", "project_id", "recipients", "enabled", "next_run_at").Updates(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить расписание"})
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *SchedulesHandler) DeleteSchedule(c *gin.Context) {
+	schedule, ok := h.ownSchedule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Расписание удалено"})
+}
+
+// RunSchedule сразу формирует и отправляет сводку за период, заканчивающийся сейчас.
+// Срок следующей плановой сводки не меняется.
+func (h *SchedulesHandler) RunSchedule(c *gin.Context) {
+	schedule, ok := h.ownSchedule(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	history, err := schedules.Deliver(h.db, h.mailer, schedule, schedules.Period(schedule.Frequency, now), now, now)
+	if err != nil && history.ID == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сформировать сводку"})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// ListHistory возвращает сформированные сводки расписания, новые сверху.
+func (h *SchedulesHandler) ListHistory(c *gin.Context) {
+	schedule, ok := h.ownSchedule(c)
+	if !ok {
+		return
+	}
+
+	var history []models.ScheduledReport
+	if err := h.db.Where("schedule_id = ?", schedule.ID).Order("created_at DESC").Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить историю"})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+func (h *SchedulesHandler) DownloadHistory(c *gin.Context) {
+	schedule, ok := h.ownSchedule(c)
+	if !ok {
+		return
+	}
+
+	var history models.ScheduledReport
+	if err := h.db.Where("schedule_id = ?", schedule.ID).First(&history, c.Param("reportId")).Error; err != nil || history.Filename == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Сводка не найдена"})
+		return
+	}
+	filePath := filepath.Join(schedules.Dir, filepath.Base(history.Filename))
+	if _, err := os.Stat(filePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Файл сводки не найден"})
+		return
+	}
+	c.FileAttachment(filePath, history.Filename)
+}
+
+// bindSchedule читает поля расписания из запроса и проверяет проект.
+func (h *SchedulesHandler) bindSchedule(c *gin.Context, schedule *models.ReportSchedule) bool {
+	var input models.ReportScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return false
+	}
+	if input.ProjectID != nil {
+		var project models.Project
+		if err := h.db.First(&project, *input.ProjectID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Проект не найден"})
+			return false
+		}
+	}
+
+	schedule.Name = input.Name
+	schedule.Frequency = input.Frequency
+	schedule.Format = input.Format
+	schedule.ProjectID = input.ProjectID
+	schedule.Project = nil
+	schedule.Recipients = input.Recipients
+	if schedule.Recipients == nil {
+		schedule.Recipients = []string{}
+	}
+	if input.Enabled != nil {
+		schedule.Enabled = *input.Enabled
+	}
+	return true
+}
+
+func (h *SchedulesHandler) ownSchedule(c *gin.Context) (models.ReportSchedule, bool) {
+	var schedule models.ReportSchedule
+	leaderID, ok := h.leaderID(c)
+	if !ok {
+		return schedule, false
+	}
+
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID расписания"})
+		return schedule, false
+	}
+	if err := h.db.Preload("User").Where("user_id = ?", leaderID).First(&schedule, scheduleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Расписание не найдено"})
+		return schedule, false
+	}
+	return schedule, true
+}
+
+func (h *SchedulesHandler) leaderID(c *gin.Context) (uint, bool) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if role != "Руководитель" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещён. Требуется роль Руководителя"})
+		return 0, false
+	}
+	return uint(userID.(float64)), true
+}
@@ -0,0 +1,21 @@
+package schedules
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *SchedulesHandler) RegisterRoutes(router *gin.Engine) {
+	schedules := router.Group("api/schedules")
+	{
+		schedules.GET("", utils.AuthMiddleware(), h.ListSchedules)
+		schedules.POST("", utils.AuthMiddleware(), h.CreateSchedule)
+		schedules.PUT("/:id", utils.AuthMiddleware(), h.UpdateSchedule)
+		schedules.DELETE("/:id", utils.AuthMiddleware(), h.DeleteSchedule)
+
+		schedules.POST("/:id/run", utils.AuthMiddleware(), h.RunSchedule)
+		schedules.GET("/:id/history", utils.AuthMiddleware(), h.ListHistory)
+		schedules.GET("/:id/history/:reportId/file", utils.AuthMiddleware(), h.DownloadHistory)
+	}
+}
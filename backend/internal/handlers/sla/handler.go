@@ -0,0 +1,289 @@
+package sla
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/calendar"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/sla"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SLAHandler struct {
+	db *gorm.DB
+}
+
+func NewSLAHandler(db *gorm.DB) *SLAHandler {
+	return &SLAHandler{db: db}
+}
+
+func (h *SLAHandler) ListPolicies(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewProject(h.db, project.ID, uint(userID.(float64)), role.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не участвуете в этом проекте"})
+		return
+	}
+
+	policies, err := sla.ProjectPolicies(h.db, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить политики SLA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+func (h *SLAHandler) UpdatePolicies(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Проект не найден"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if role != "Админ" && !access.IsProjectManager(h.db, project.ID, uint(userID.(float64))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, project.ID) {
+		return
+	}
+
+	var input []models.SLAPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	policies := make([]models.SLAPolicy, 0, len(input))
+	seen := map[string]bool{}
+	for _, item := range input {
+		if seen[item.Priority] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Приоритет '" + item.Priority + "' указан несколько раз"})
+			return
+		}
+		seen[item.Priority] = true
+		if item.ResolutionHours < item.ResponseHours {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Срок устранения не может быть меньше срока реакции"})
+			return
+		}
+
+		extension := item.ExtensionHours
+		if extension == 0 {
+			extension = sla.DefaultPolicies[item.Priority].ExtensionHours
+		}
+
+		policies = append(policies, models.SLAPolicy{
+			ProjectID:       project.ID,
+			Priority:        item.Priority,
+			ResponseHours:   item.ResponseHours,
+			ResolutionHours: item.ResolutionHours,
+			ExtensionHours:  extension,
+		})
+	}
+
+	if len(policies) > 0 {
+		if err := h.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "project_id"}, {Name: "priority"}},
+			DoUpdates: clause.AssignmentColumns([]string{"response_hours", "resolution_hours", "extension_hours"}),
+		}).Create(&policies).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить политики SLA"})
+			return
+		}
+	}
+
+	result, err := sla.ProjectPolicies(h.db, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить политики SLA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *SLAHandler) LeaderCompliance(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "Руководитель" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Доступ запрещён. Требуется роль Руководителя"})
+		return
+	}
+
+	query := h.db.Table("defects d").
+		Joins("JOIN projects p ON p.id = d.project_id").
+		Where("d.deleted_at IS NULL")
+
+	if value := c.Query("project_id"); value != "" {
+		projectID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID проекта"})
+			return
+		}
+		query = query.Where("d.project_id = ?", projectID)
+	}
+	if from := c.Query("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты 'from'"})
+			return
+		}
+		query = query.Where("d.created_at >= ?", date)
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты 'to'"})
+			return
+		}
+		query = query.Where("d.created_at < ?", date.AddDate(0, 0, 1))
+	}
+
+	byProject, err := complianceBy(query.Session(&gorm.Session{}), "p.id", "p.name", "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику SLA"})
+		return
+	}
+
+	byManager, err := complianceBy(query.Session(&gorm.Session{}), "u.id", "CONCAT(u.last_name, ' ', u.first_name)", "JOIN users u ON u.id = p.manager_id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику SLA"})
+		return
+	}
+
+	byAssignee, err := complianceBy(query.Session(&gorm.Session{}), "u.id", "CONCAT(u.last_name, ' ', u.first_name)", "JOIN users u ON u.id = d.assignee_id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику SLA"})
+		return
+	}
+
+	if err := h.fillResolutionHours(query.Session(&gorm.Session{}), byProject, byManager, byAssignee); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить статистику SLA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"projects":  byProject,
+		"managers":  byManager,
+		"assignees": byAssignee,
+	})
+}
+
+func complianceBy(query *gorm.DB, idExpr, nameExpr, join string) ([]models.SLACompliance, error) {
+	if join != "" {
+		query = query.Joins(join)
+	}
+
+	var rows []models.SLACompliance
+	err := query.Select(idExpr + ` AS id, ` + nameExpr + ` AS name,
+			COUNT(*) FILTER (WHERE d.response_due_date IS NOT NULL AND (d.assigned_at IS NOT NULL OR d.response_due_date < NOW())) AS response_total,
+			COUNT(*) FILTER (WHERE d.assigned_at IS NOT NULL AND d.assigned_at <= d.response_due_date) AS response_met,
+			COUNT(*) FILTER (WHERE d.due_date IS NOT NULL AND (d.closed_at IS NOT NULL OR d.due_date < NOW())) AS resolution_total,
+			COUNT(*) FILTER (WHERE d.closed_at IS NOT NULL AND d.closed_at <= d.due_date) AS resolution_met`).
+		Group(idExpr + ", " + nameExpr).
+		Order(idExpr).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		rows[i].ResponseRate = percent(rows[i].ResponseMet, rows[i].ResponseTotal)
+		rows[i].ResolutionRate = percent(rows[i].ResolutionMet, rows[i].ResolutionTotal)
+	}
+	return rows, nil
+}
+
+// fillResolutionHours считает среднее время устранения в рабочих часах календаря каждого проекта.
+func (h *SLAHandler) fillResolutionHours(query *gorm.DB, byProject, byManager, byAssignee []models.SLACompliance) error {
+	type closedDefect struct {
+		ProjectID  uint
+		ManagerID  uint
+		AssigneeID *uint
+		StartedAt  time.Time
+		ClosedAt   time.Time
+	}
+
+	var rows []closedDefect
+	if err := query.
+		Select("d.project_id, p.manager_id, d.assignee_id, COALESCE(d.assigned_at, d.created_at) AS started_at, d.closed_at").
+		Where("d.closed_at IS NOT NULL").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	type total struct {
+		hours float64
+		count int
+	}
+	projects := map[uint]*total{}
+	managers := map[uint]*total{}
+	assignees := map[uint]*total{}
+	add := func(m map[uint]*total, id uint, hours float64) {
+		if m[id] == nil {
+			m[id] = &total{}
+		}
+		m[id].hours += hours
+		m[id].count++
+	}
+
+	calendars := map[uint]*calendar.Calendar{}
+	for _, row := range rows {
+		cal, ok := calendars[row.ProjectID]
+		if !ok {
+			cal = calendar.ForProject(h.db, row.ProjectID)
+			calendars[row.ProjectID] = cal
+		}
+
+		hours := cal.WorkingDuration(row.StartedAt, row.ClosedAt).Hours()
+		add(projects, row.ProjectID, hours)
+		add(managers, row.ManagerID, hours)
+		if row.AssigneeID != nil {
+			add(assignees, *row.AssigneeID, hours)
+		}
+	}
+
+	fill := func(items []models.SLACompliance, m map[uint]*total) {
+		for i := range items {
+			if t := m[items[i].ID]; t != nil {
+				items[i].AvgResolutionHours = float64(int(t.hours/float64(t.count)*100)) / 100
+			}
+		}
+	}
+	fill(byProject, projects)
+	fill(byManager, managers)
+	fill(byAssignee, assignees)
+	return nil
+}
+
+// percent возвращает долю в процентах; без дефектов со сроком доля не определена.
+func percent(part, total int64) *float64 {
+	if total == 0 {
+		return nil
+	}
+	rate := float64(part*10000/total) / 100
+	return &rate
+}
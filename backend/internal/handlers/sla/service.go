@@ -0,0 +1,17 @@
+package sla
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *SLAHandler) RegisterRoutes(router *gin.Engine) {
+	sla := router.Group("api/sla")
+	{
+		sla.GET("/policies/:project_id", utils.AuthMiddleware(), h.ListPolicies)
+		sla.GET("/compliance", utils.AuthMiddleware(), h.LeaderCompliance)
+
+		sla.PUT("/policies/:project_id", utils.AuthMiddleware(), h.UpdatePolicies)
+	}
+}
@@ -0,0 +1,188 @@
+package worklogs
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/access"
+	"systemacontrolya/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkLogsHandler struct {
+	db *gorm.DB
+}
+
+func NewWorkLogsHandler(db *gorm.DB) *WorkLogsHandler {
+	return &WorkLogsHandler{db: db}
+}
+
+// ListDefectWorkLogs возвращает записи о работе по дефекту, сумму часов и оценку менеджера.
+func (h *WorkLogsHandler) ListDefectWorkLogs(c *gin.Context) {
+	defect, ok := h.findDefect(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	if !access.CanViewDefect(h.db, uint(userID.(float64)), role.(string), defect) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+
+	var entries []models.WorkLog
+	if err := h.db.Preload("User", models.WithDeleted).Where("defect_id = ?", defect.ID).Order("work_date, id").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить трудозатраты"})
+		return
+	}
+	var total float64
+	for _, entry := range entries {
+		total += entry.Hours
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "total_hours": total, "estimate_hours": defect.EstimateHours})
+}
+
+// AddWorkLog записывает работу по дефекту. Записывать может только назначенный исполнитель.
+func (h *WorkLogsHandler) AddWorkLog(c *gin.Context) {
+	defect, ok := h.findDefect(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+	assigneeID := uint(userID.(float64))
+	if defect.AssigneeID == nil || *defect.AssigneeID != assigneeID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Трудозатраты записывает назначенный исполнитель"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, defect.ProjectID) {
+		return
+	}
+
+	entry := models.WorkLog{DefectID: defect.ID, UserID: assigneeID}
+	if !h.bindWorkLog(c, &entry) {
+		return
+	}
+	if err := h.db.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить трудозатраты"})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// EditWorkLog исправляет свою запись о работе.
+func (h *WorkLogsHandler) EditWorkLog(c *gin.Context) {
+	entry, ok := h.findWorkLog(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+	if entry.UserID != uint(userID.(float64)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Исправить можно только свою запись"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, entry.Defect.ProjectID) {
+		return
+	}
+
+	if !h.bindWorkLog(c, &entry) {
+		return
+	}
+	if err := h.db.Model(&entry).Select("work_date", "hours", "description").Updates(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить трудозатраты"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteWorkLog удаляет запись; удалить может автор записи или менеджер проекта.
+func (h *WorkLogsHandler) DeleteWorkLog(c *gin.Context) {
+	entry, ok := h.findWorkLog(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+	currentUserID := uint(userID.(float64))
+	if entry.UserID != currentUserID && !access.IsProjectManager(h.db, entry.Defect.ProjectID, currentUserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		return
+	}
+	if !access.RequireActiveProject(c, h.db, entry.Defect.ProjectID) {
+		return
+	}
+
+	if err := h.db.Delete(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Запись удалена"})
+}
+
+// bindWorkLog читает запись из запроса. Дата не может быть в будущем, а за день у пользователя
+// набирается не больше 24 часов по всем дефектам.
+func (h *WorkLogsHandler) bindWorkLog(c *gin.Context, entry *models.WorkLog) bool {
+	var input models.WorkLogInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return false
+	}
+	workDate, err := time.Parse("2006-01-02", input.WorkDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"})
+		return false
+	}
+	if workDate.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя записать работу на будущую дату"})
+		return false
+	}
+
+	var logged float64
+	if err := h.db.Model(&models.WorkLog{}).
+		Where("user_id = ? AND work_date = ? AND id <> ?", entry.UserID, workDate.Format("2006-01-02"), entry.ID).
+		Select("COALESCE(SUM(hours), 0)::float8").
+		Scan(&logged).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось проверить трудозатраты"})
+		return false
+	}
+	if logged+input.Hours > 24 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("За %s уже записано %.2f ч, в сутках не больше 24 ч", workDate.Format("02.01.2006"), logged)})
+		return false
+	}
+
+	entry.WorkDate = workDate
+	entry.Hours = input.Hours
+	entry.Description = input.Description
+	return true
+}
+
+func (h *WorkLogsHandler) findDefect(c *gin.Context) (models.Defect, bool) {
+	var defect models.Defect
+	defectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID дефекта"})
+		return defect, false
+	}
+	if err := h.db.First(&defect, defectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Дефект не найден"})
+		return defect, false
+	}
+	return defect, true
+}
+
+func (h *WorkLogsHandler) findWorkLog(c *gin.Context) (models.WorkLog, bool) {
+	var entry models.WorkLog
+	entryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID записи"})
+		return entry, false
+	}
+	if err := h.db.Preload("Defect").First(&entry, entryID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Запись не найдена"})
+		return entry, false
+	}
+	return entry, true
+}
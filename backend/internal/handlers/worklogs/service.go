@@ -0,0 +1,20 @@
+package worklogs
+
+import (
+	"systemacontrolya/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *WorkLogsHandler) RegisterRoutes(router *gin.Engine) {
+	worklogs := router.Group("api/worklogs")
+	{
+		worklogs.GET("/defect/:id", utils.AuthMiddleware(), h.ListDefectWorkLogs)
+
+		worklogs.POST("/defect/:id", utils.AuthMiddleware(), h.AddWorkLog)
+
+		worklogs.PUT("/edit/:id", utils.AuthMiddleware(), h.EditWorkLog)
+
+		worklogs.DELETE("/delete/:id", utils.AuthMiddleware(), h.DeleteWorkLog)
+	}
+}
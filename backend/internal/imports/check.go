@@ -0,0 +1,274 @@
+package imports
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"systemacontrolya/internal/customfields"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/sla"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+const assigneeRoleID = 5
+
+var dateLayouts = []string{"02.01.2006", "2006-01-02", "02.01.2006 15:04", "2006-01-02 15:04"}
+
+// Preview — строка файла в том виде, в каком она станет дефектом.
+type Preview struct {
+	Title        string         `json:"title"`
+	Description  string         `json:"description"`
+	Priority     string         `json:"priority"`
+	ProjectID    uint           `json:"project_id,omitempty"`
+	Project      string         `json:"project,omitempty"`
+	AssigneeID   *uint          `json:"assignee_id,omitempty"`
+	Assignee     string         `json:"assignee,omitempty"`
+	DueDate      *time.Time     `json:"duedate,omitempty"`
+	CustomFields map[string]any `json:"custom_fields,omitempty"`
+}
+
+type RowResult struct {
+	Line    int      `json:"line"`
+	Preview Preview  `json:"preview"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// Checker проверяет строки от имени загружающего пользователя. Проекты и пользователи
+// читаются один раз, поэтому проверка файла не зависит от числа строк.
+type Checker struct {
+	db       *gorm.DB
+	userID   uint
+	role     string
+	now      time.Time
+	projects map[string]models.Project
+	users    map[string]models.User
+	members  map[[2]uint]string
+}
+
+func NewChecker(db *gorm.DB, userID uint, role string, now time.Time) (*Checker, error) {
+	checker := &Checker{
+		db:       db,
+		userID:   userID,
+		role:     role,
+		now:      now,
+		projects: map[string]models.Project{},
+		users:    map[string]models.User{},
+		members:  map[[2]uint]string{},
+	}
+
+	var projects []models.Project
+	if err := db.Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		checker.projects[strconv.FormatUint(uint64(project.ID), 10)] = project
+		checker.projects[strings.ToLower(project.Name)] = project
+	}
+
+	var users []models.User
+	if err := db.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		checker.users[strings.ToLower(user.Email)] = user
+		checker.users[strings.ToLower(user.FullName())] = user
+	}
+
+	var members []models.ProjectMember
+	if err := db.Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		checker.members[[2]uint{member.ProjectID, member.UserID}] = member.Role
+	}
+	return checker, nil
+}
+
+// Check проверяет все строки таблицы. Строка без ошибок готова к созданию через Defect.
+func (c *Checker) Check(table *Table, mapping Mapping) []RowResult {
+	results := make([]RowResult, 0, len(table.Rows))
+	for _, row := range table.Rows {
+		results = append(results, c.checkRow(row, mapping))
+	}
+	return results
+}
+
+func (c *Checker) checkRow(row Row, mapping Mapping) RowResult {
+	cell := func(field string) string {
+		column, ok := mapping[field]
+		if !ok {
+			return ""
+		}
+		return row.Cell(column)
+	}
+
+	result := RowResult{Line: row.Line}
+	fail := func(format string, args ...any) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+	preview := &result.Preview
+
+	preview.Title = cell("title")
+	switch {
+	case preview.Title == "":
+		fail("Не указано название")
+	case len([]rune(preview.Title)) > 200:
+		fail("Название длиннее 200 символов")
+	}
+
+	preview.Description = cell("description")
+	if preview.Description == "" {
+		fail("Не указано описание")
+	}
+
+	if priority, ok := parsePriority(cell("priority")); ok {
+		preview.Priority = priority
+	} else {
+		fail("Неизвестный приоритет «%s»: ожидается low, medium, high или critical", cell("priority"))
+	}
+
+	project, projectOK := c.projects[strings.ToLower(cell("project"))]
+	switch {
+	case cell("project") == "":
+		fail("Не указан проект")
+	case !projectOK:
+		fail("Проект «%s» не найден", cell("project"))
+	case c.role != "Админ" && !c.memberOf(project.ID, c.userID, "engineer", "manager"):
+		fail("Вы не участвуете в проекте «%s»", project.Name)
+	case project.Status == "archived":
+		fail("Проект «%s» в архиве", project.Name)
+	default:
+		preview.ProjectID = project.ID
+		preview.Project = project.Name
+	}
+
+	if value := cell("assignee"); value != "" {
+		assignee, ok := c.users[strings.ToLower(value)]
+		switch {
+		case !ok:
+			fail("Исполнитель «%s» не найден", value)
+		case assignee.RoleID != assigneeRoleID:
+			fail("Пользователь «%s» не является исполнителем", value)
+		case assignee.Status != "active":
+			fail("Исполнитель «%s» деактивирован", value)
+		case projectOK && !c.memberOf(project.ID, c.userID, "manager"):
+			fail("Назначать исполнителя может только менеджер проекта")
+		case projectOK && !c.memberOf(project.ID, assignee.ID, "assignee"):
+			fail("Исполнитель «%s» не входит в команду проекта", value)
+		default:
+			preview.AssigneeID = &assignee.ID
+			preview.Assignee = assignee.FullName()
+		}
+	}
+
+	if value := cell("due_date"); value != "" {
+		due, err := parseDate(value)
+		switch {
+		case err != nil:
+			fail("Неверный срок «%s»: ожидается дата ДД.ММ.ГГГГ", value)
+		case due.Before(c.now):
+			fail("Срок выполнения не может быть в прошлом")
+		default:
+			preview.DueDate = &due
+		}
+	}
+
+	custom := map[string]any{}
+	for field := range mapping {
+		if isCustom(field) {
+			if value := cell(field); value != "" {
+				custom[strings.TrimPrefix(field, customfields.FilterPrefix)] = value
+			}
+		}
+	}
+	if preview.ProjectID != 0 {
+		values, err := customfields.Validate(c.db, preview.ProjectID, nil, custom)
+		if err != nil {
+			fail("%s", capitalize(err.Error()))
+		} else {
+			preview.CustomFields = values
+		}
+	}
+
+	return result
+}
+
+// Defect собирает дефект из проверенной строки: сроки SLA выставляются так же, как при создании
+// и назначении через API.
+func (c *Checker) Defect(preview Preview, jobID uint) models.Defect {
+	defect := models.Defect{
+		Title:        preview.Title,
+		Description:  preview.Description,
+		Priority:     preview.Priority,
+		Status:       "new",
+		ProjectID:    preview.ProjectID,
+		AuthorID:     c.userID,
+		AssigneeID:   preview.AssigneeID,
+		DueDate:      preview.DueDate,
+		Attachments:  []string{},
+		CustomFields: preview.CustomFields,
+		ImportJobID:  &jobID,
+	}
+	// Колонки attachments и custom_fields NOT NULL: пустые значения пишутся как [] и {}, а не NULL.
+	if defect.CustomFields == nil {
+		defect.CustomFields = map[string]any{}
+	}
+	sla.ApplyOnCreate(c.db, &defect, c.now)
+	if defect.AssigneeID != nil {
+		defect.Status = "in_progress"
+		sla.ApplyOnAssign(c.db, &defect, c.now)
+	}
+	return defect
+}
+
+func (c *Checker) memberOf(projectID, userID uint, roles ...string) bool {
+	role, ok := c.members[[2]uint{projectID, userID}]
+	return ok && slices.Contains(roles, role)
+}
+
+func parsePriority(value string) (string, bool) {
+	value = strings.ToLower(value)
+	for code, name := range models.PriorityNames {
+		if value == code || value == strings.ToLower(name) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// parseDate понимает текстовые даты и серийные номера дат Excel.
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			if !strings.Contains(layout, "15") {
+				date = date.Add(24*time.Hour - time.Second)
+			}
+			return date, nil
+		}
+	}
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	date, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if serial == float64(int(serial)) {
+		date = date.Add(24*time.Hour - time.Second)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), 0, time.Local), nil
+}
+
+func capitalize(message string) string {
+	runes := []rune(message)
+	if len(runes) == 0 {
+		return message
+	}
+	return strings.ToUpper(string(runes[0])) + string(runes[1:])
+}
@@ -0,0 +1,115 @@
+package imports
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"systemacontrolya/internal/customfields"
+)
+
+// Fields — поля дефекта, которые можно загрузить из файла, в порядке вывода.
+var Fields = []string{"title", "description", "priority", "project", "assignee", "due_date"}
+
+var requiredFields = []string{"title", "description", "priority", "project"}
+
+// aliases — заголовки колонок, по которым поле находится автоматически (в нижнем регистре).
+var aliases = map[string][]string{
+	"title":       {"title", "название", "заголовок", "дефект"},
+	"description": {"description", "описание"},
+	"priority":    {"priority", "приоритет"},
+	"project":     {"project", "project_id", "проект"},
+	"assignee":    {"assignee", "assignee_email", "исполнитель", "email исполнителя"},
+	"due_date":    {"due_date", "duedate", "срок", "срок устранения"},
+}
+
+// Mapping сопоставляет поле дефекта с номером колонки файла.
+// Колонки с заголовком cf.<key> попадают в пользовательские поля проекта.
+type Mapping map[string]int
+
+// DetectMapping подбирает колонки по заголовкам; overrides — JSON вида {"title": "Заголовок в файле"},
+// пустая строка в нём отключает поле.
+func DetectMapping(header []string, overrides string) (Mapping, error) {
+	mapping := Mapping{}
+	for column, title := range header {
+		name := strings.ToLower(title)
+		if isCustom(name) {
+			mapping[name] = column
+			continue
+		}
+		for field, names := range aliases {
+			if _, taken := mapping[field]; !taken && slices.Contains(names, name) {
+				mapping[field] = column
+			}
+		}
+	}
+
+	if strings.TrimSpace(overrides) == "" {
+		return mapping, nil
+	}
+	var manual map[string]string
+	if err := json.Unmarshal([]byte(overrides), &manual); err != nil {
+		return nil, fmt.Errorf("сопоставление колонок должно быть объектом JSON")
+	}
+	for field, title := range manual {
+		if !slices.Contains(Fields, field) && !isCustom(field) {
+			return nil, fmt.Errorf("неизвестное поле %q", field)
+		}
+		if title == "" {
+			delete(mapping, field)
+			continue
+		}
+		column := slices.IndexFunc(header, func(h string) bool { return strings.EqualFold(h, title) })
+		if column < 0 {
+			return nil, fmt.Errorf("в файле нет колонки «%s»", title)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// Missing возвращает обязательные поля, для которых не нашлось колонки.
+func (m Mapping) Missing() []string {
+	var missing []string
+	for _, field := range requiredFields {
+		if _, ok := m[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// Describe возвращает сопоставление в виде «поле → заголовок колонки» для предпросмотра.
+func (m Mapping) Describe(header []string) map[string]*string {
+	described := make(map[string]*string, len(Fields))
+	for _, field := range Fields {
+		described[field] = nil
+	}
+	for field, column := range m {
+		described[field] = &header[column]
+	}
+	return described
+}
+
+// Unmapped возвращает заголовки колонок, которые не будут загружены.
+func (m Mapping) Unmapped(header []string) []string {
+	unmapped := []string{}
+	for column, title := range header {
+		used := false
+		for _, mapped := range m {
+			if mapped == column {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unmapped = append(unmapped, title)
+		}
+	}
+	return unmapped
+}
+
+func isCustom(field string) bool {
+	return strings.HasPrefix(field, customfields.FilterPrefix) && customfields.ValidKey(strings.TrimPrefix(field, customfields.FilterPrefix))
+}
@@ -0,0 +1,118 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxRows ограничивает размер одного импорта, чтобы проверка и транзакция оставались быстрыми.
+const MaxRows = 2000
+
+// Table — содержимое загруженного файла: заголовок и строки данных без пустых строк.
+type Table struct {
+	Header []string
+	Rows   []Row
+}
+
+type Row struct {
+	Line   int
+	Values []string
+}
+
+// Read разбирает CSV или XLSX по расширению файла. Из XLSX читается первый лист.
+func Read(filename string, r io.Reader) (*Table, error) {
+	var records [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		records, err = readCSV(r)
+	case ".xlsx":
+		records, err = readXLSX(r)
+	default:
+		return nil, fmt.Errorf("поддерживаются только файлы CSV и XLSX")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("файл пуст")
+	}
+
+	table := &Table{}
+	for _, value := range records[0] {
+		table.Header = append(table.Header, strings.TrimSpace(value))
+	}
+	for i, record := range records[1:] {
+		if blank(record) {
+			continue
+		}
+		if len(table.Rows) == MaxRows {
+			return nil, fmt.Errorf("в файле больше %d строк", MaxRows)
+		}
+		table.Rows = append(table.Rows, Row{Line: i + 2, Values: record})
+	}
+	if len(table.Rows) == 0 {
+		return nil, fmt.Errorf("в файле нет строк с данными")
+	}
+	return table, nil
+}
+
+// Cell возвращает значение колонки или пустую строку, если строка короче заголовка.
+func (r Row) Cell(column int) string {
+	if column < 0 || column >= len(r.Values) {
+		return ""
+	}
+	return strings.TrimSpace(r.Values[column])
+}
+
+// readCSV понимает выгрузки Excel: BOM в начале и «;» в качестве разделителя.
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	reader := csv.NewReader(bytes.NewReader(data))
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать CSV: %v", err)
+	}
+	return records, nil
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать XLSX: %v", err)
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("в книге нет листов")
+	}
+	// Сырые значения нужны для дат: ячейка с датой хранит серийный номер, который разбирается в parseDate.
+	return file.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+}
+
+func blank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package labels
+
+import (
+	"fmt"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ProjectLabels загружает метки по ID и проверяет, что все они принадлежат проекту.
+func ProjectLabels(db *gorm.DB, projectID uint, ids []uint) ([]models.Label, error) {
+	labels := []models.Label{}
+	if len(ids) == 0 {
+		return labels, nil
+	}
+
+	if err := db.Where("id IN ? AND project_id = ?", ids, projectID).Find(&labels).Error; err != nil {
+		return nil, err
+	}
+	if len(labels) != len(uniqueIDs(ids)) {
+		return nil, fmt.Errorf("метки должны принадлежать проекту дефекта")
+	}
+	return labels, nil
+}
+
+// FilterDefects оставляет дефекты, у которых есть хотя бы одна из меток.
+func FilterDefects(query *gorm.DB, ids []uint) *gorm.DB {
+	if len(ids) == 0 {
+		return query
+	}
+	return query.Where("defects.id IN (SELECT defect_id FROM defect_labels WHERE label_id IN ?)", ids)
+}
+
+func uniqueIDs(ids []uint) map[uint]struct{} {
+	set := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
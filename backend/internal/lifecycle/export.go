@@ -0,0 +1,157 @@
+package lifecycle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"systemacontrolya/internal/costs"
+	"systemacontrolya/internal/customfields"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/xlsxexport"
+
+	"gorm.io/gorm"
+)
+
+// ExportDir — каталог выгрузок проектов перед окончательным удалением.
+const ExportDir = "uploads/exports"
+
+// Export сохраняет все данные проекта в ZIP-архив: реестр дефектов в XLSX и полные данные в JSON.
+// Возвращает имя файла внутри ExportDir.
+func Export(db *gorm.DB, project models.Project, now time.Time) (string, error) {
+	if err := os.MkdirAll(ExportDir, 0o755); err != nil {
+		return "", err
+	}
+	filename := fmt.Sprintf("project_%d_%s.zip", project.ID, now.Format("20060102_150405"))
+	file, err := os.Create(filepath.Join(ExportDir, filename))
+	if err != nil {
+		return "", err
+	}
+
+	if err := writeArchive(db, project, file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return filename, nil
+}
+
+func writeArchive(db *gorm.DB, project models.Project, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	var defects []models.Defect
+	if err := db.Where("project_id = ?", project.ID).
+		Preload("Project").Preload("Author", models.WithDeleted).Preload("Assignee", models.WithDeleted).Preload("Labels").Preload("Location").Preload("Organization").
+		Order("id").Find(&defects).Error; err != nil {
+		return err
+	}
+	var reports []models.Report
+	if err := db.Where("project_id = ?", project.ID).Order("id").Find(&reports).Error; err != nil {
+		return err
+	}
+	var reviews []models.ReportReview
+	if err := db.Where("report_id IN (?)", db.Model(&models.Report{}).Select("id").Where("project_id = ?", project.ID)).
+		Order("id").Find(&reviews).Error; err != nil {
+		return err
+	}
+	var members []models.ProjectMember
+	if err := db.Where("project_id = ?", project.ID).Find(&members).Error; err != nil {
+		return err
+	}
+	var locations []models.Location
+	if err := db.Where("project_id = ?", project.ID).Order("path").Find(&locations).Error; err != nil {
+		return err
+	}
+	var labels []models.Label
+	if err := db.Where("project_id = ?", project.ID).Find(&labels).Error; err != nil {
+		return err
+	}
+	var materials []models.Material
+	if err := db.Where("project_id = ?", project.ID).Order("name").Find(&materials).Error; err != nil {
+		return err
+	}
+	var usage []models.DefectMaterial
+	if err := db.Where("defect_id IN (?)", db.Model(&models.Defect{}).Select("id").Where("project_id = ?", project.ID)).
+		Order("id").Find(&usage).Error; err != nil {
+		return err
+	}
+	var assignments []models.DefectAssignment
+	if err := db.Where("defect_id IN (?)", db.Model(&models.Defect{}).Select("id").Where("project_id = ?", project.ID)).
+		Order("id").Find(&assignments).Error; err != nil {
+		return err
+	}
+	fields, err := customfields.ProjectFields(db, project.ID)
+	if err != nil {
+		return err
+	}
+	ids := make([]uint, 0, len(defects))
+	for _, defect := range defects {
+		ids = append(ids, defect.ID)
+	}
+	defectCosts, err := costs.ForDefects(db, ids)
+	if err != nil {
+		return err
+	}
+
+	documents := []struct {
+		name string
+		data any
+	}{
+		{"project.json", project},
+		{"members.json", members},
+		{"defects.json", defects},
+		{"reports.json", reports},
+		{"report_reviews.json", reviews},
+		{"locations.json", locations},
+		{"labels.json", labels},
+		{"materials.json", materials},
+		{"defect_materials.json", usage},
+		{"defect_assignments.json", assignments},
+		{"custom_fields.json", fields},
+	}
+	for _, document := range documents {
+		entry, err := archive.Create(document.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(document.data); err != nil {
+			return err
+		}
+	}
+
+	register, err := xlsxexport.NewRegister()
+	if err != nil {
+		return err
+	}
+	sheet, err := register.StartSheet(project.Name, fields)
+	if err != nil {
+		return err
+	}
+	for _, defect := range defects {
+		if err := sheet.Add(defect, defectCosts[defect.ID]); err != nil {
+			return err
+		}
+	}
+	if err := sheet.Close(); err != nil {
+		return err
+	}
+	entry, err := archive.Create("defects.xlsx")
+	if err != nil {
+		return err
+	}
+	if err := register.Write(entry); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
@@ -0,0 +1,62 @@
+package lifecycle
+
+import (
+	"fmt"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Transitions — допустимые переходы статуса проекта вне архивации. В архив проект переводится
+// только через проверку закрытия, из архива — восстановлением.
+var Transitions = map[string][]string{
+	"planning": {"active"},
+	"active":   {"planning", "warranty"},
+	"warranty": {"active"},
+}
+
+// CloseOutReport — результат проверки перед архивацией проекта.
+type CloseOutReport struct {
+	Ready          bool             `json:"ready"`
+	OpenDefects    int64            `json:"open_defects"`
+	ByStatus       map[string]int64 `json:"by_status"`
+	PendingReports int64            `json:"pending_reports"`
+	Problems       []string         `json:"problems"`
+}
+
+// CloseOut проверяет, можно ли закрыть проект: все дефекты должны быть закрыты, а отчёты — рассмотрены.
+func CloseOut(db *gorm.DB, projectID uint) (CloseOutReport, error) {
+	report := CloseOutReport{ByStatus: map[string]int64{}, Problems: []string{}}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Model(&models.Defect{}).
+		Select("status, COUNT(*) AS count").
+		Where("project_id = ? AND status <> ?", projectID, "closed").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return report, err
+	}
+	for _, row := range rows {
+		report.ByStatus[row.Status] = row.Count
+		report.OpenDefects += row.Count
+	}
+
+	if err := db.Model(&models.Report{}).
+		Where("project_id = ? AND status = ?", projectID, "pending").
+		Count(&report.PendingReports).Error; err != nil {
+		return report, err
+	}
+
+	if report.OpenDefects > 0 {
+		report.Problems = append(report.Problems, fmt.Sprintf("Открытых дефектов: %d", report.OpenDefects))
+	}
+	if report.PendingReports > 0 {
+		report.Problems = append(report.Problems, fmt.Sprintf("Отчётов на рассмотрении: %d", report.PendingReports))
+	}
+	report.Ready = len(report.Problems) == 0
+	return report, nil
+}
@@ -0,0 +1,260 @@
+package links
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"systemacontrolya/internal/audit"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/sla"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrCycle = errors.New("связь образует цикл")
+
+// singleLinks — типы связей, которых у дефекта может быть не больше одной.
+var singleLinks = map[string]string{
+	"duplicate_of": "дефект уже отмечен как дубликат",
+	"parent":       "у дефекта уже есть родительский дефект",
+}
+
+// Add связывает дефект source с input.TargetID. Для duplicate_of, blocks и parent проверяется, что связь
+// не замыкает цикл. Дубликат сразу закрывается, у родителя пересчитывается статус.
+func Add(db *gorm.DB, source models.Defect, input models.DefectLinkInput, actorID uint, now time.Time) (models.DefectLink, error) {
+	link := models.DefectLink{Type: input.Type, SourceID: source.ID, TargetID: input.TargetID}
+	if actorID != 0 {
+		link.CreatedByID = &actorID
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Оба дефекта блокируются до конца транзакции, чтобы параллельные запросы не прошли
+		// проверки на цикл и дубликат одновременно.
+		if input.TargetID == source.ID {
+			return fmt.Errorf("нельзя связать дефект с самим собой")
+		}
+		var locked []models.Defect
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{source.ID, input.TargetID}).Order("id").Find(&locked).Error; err != nil {
+			return err
+		}
+		var target models.Defect
+		for _, defect := range locked {
+			if defect.ID == source.ID {
+				source = defect
+			} else {
+				target = defect
+			}
+		}
+		if target.ID == 0 {
+			return fmt.Errorf("связанный дефект не найден")
+		}
+		if target.ProjectID != source.ProjectID {
+			return fmt.Errorf("связывать можно только дефекты одного проекта")
+		}
+
+		var count int64
+		query := tx.Model(&models.DefectLink{}).Where("type = ?", link.Type)
+		if link.Type == "relates_to" {
+			query = query.Where("(source_id = ? AND target_id = ?) OR (source_id = ? AND target_id = ?)", source.ID, target.ID, target.ID, source.ID)
+		} else {
+			query = query.Where("source_id = ? AND target_id = ?", source.ID, target.ID)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("такая связь уже есть")
+		}
+
+		if message, ok := singleLinks[link.Type]; ok {
+			if err := tx.Model(&models.DefectLink{}).Where("source_id = ? AND type = ?", source.ID, link.Type).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return errors.New(message)
+			}
+		}
+		if link.Type != "relates_to" {
+			cycle, err := reachable(tx, link.Type, target.ID, source.ID)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return ErrCycle
+			}
+		}
+
+		if err := tx.Create(&link).Error; err != nil {
+			return err
+		}
+		switch link.Type {
+		case "duplicate_of":
+			if source.Status == "closed" {
+				return nil
+			}
+			if err := CheckClose(tx, source.ID); err != nil {
+				return err
+			}
+			if err := setStatus(tx, source, "closed", actorID, now, fmt.Sprintf("Закрыт как дубликат дефекта №%d", target.ID)); err != nil {
+				return err
+			}
+			return StatusChanged(tx, source.ID, actorID, now)
+		case "parent":
+			return RollUp(tx, target.ID, actorID, now)
+		}
+		return nil
+	})
+	return link, err
+}
+
+// Remove удаляет связь; после удаления подзадачи статус родителя пересчитывается.
+func Remove(db *gorm.DB, link models.DefectLink, actorID uint, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&link).Error; err != nil {
+			return err
+		}
+		if link.Type == "parent" {
+			return RollUp(tx, link.TargetID, actorID, now)
+		}
+		return nil
+	})
+}
+
+// OpenBlockers возвращает незакрытые дефекты, которые блокируют defectID.
+func OpenBlockers(db *gorm.DB, defectID uint) ([]models.Defect, error) {
+	var blockers []models.Defect
+	err := db.Joins("JOIN defect_links l ON l.source_id = defects.id AND l.type = ?", "blocks").
+		Where("l.target_id = ? AND defects.status <> ?", defectID, "closed").
+		Order("defects.id").
+		Find(&blockers).Error
+	return blockers, err
+}
+
+// CheckClose возвращает ошибку, если дефект нельзя закрыть из-за открытых блокирующих дефектов.
+func CheckClose(db *gorm.DB, defectID uint) error {
+	blockers, err := OpenBlockers(db, defectID)
+	if err != nil {
+		return err
+	}
+	if len(blockers) == 0 {
+		return nil
+	}
+	numbers := make([]string, 0, len(blockers))
+	for _, blocker := range blockers {
+		numbers = append(numbers, fmt.Sprintf("№%d", blocker.ID))
+	}
+	return fmt.Errorf("дефект нельзя закрыть, пока открыты блокирующие дефекты: %s", strings.Join(numbers, ", "))
+}
+
+// StatusChanged пересчитывает статус родителя после смены статуса подзадачи defectID.
+func StatusChanged(db *gorm.DB, defectID, actorID uint, now time.Time) error {
+	var parentIDs []uint
+	if err := db.Model(&models.DefectLink{}).Where("source_id = ? AND type = ?", defectID, "parent").Pluck("target_id", &parentIDs).Error; err != nil {
+		return err
+	}
+	for _, parentID := range parentIDs {
+		if err := RollUp(db, parentID, actorID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RollUp выводит статус родителя из подзадач: все закрыты — закрыт (устранён, если его держат
+// блокирующие дефекты), все устранены или закрыты — устранён, работа по любой началась — в работе.
+// Если устранённый или закрытый родитель получил незавершённую подзадачу, он переоткрывается.
+// Пересчёт поднимается вверх по иерархии.
+func RollUp(db *gorm.DB, parentID, actorID uint, now time.Time) error {
+	var parent models.Defect
+	if err := db.First(&parent, parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var children []string
+	if err := db.Model(&models.Defect{}).
+		Joins("JOIN defect_links l ON l.source_id = defects.id AND l.type = ?", "parent").
+		Where("l.target_id = ?", parent.ID).
+		Pluck("defects.status", &children).Error; err != nil {
+		return err
+	}
+
+	status := rollUpStatus(parent.Status, children)
+	if status == "closed" {
+		blockers, err := OpenBlockers(db, parent.ID)
+		if err != nil {
+			return err
+		}
+		if len(blockers) > 0 {
+			status = "resolved"
+		}
+	}
+	if status == parent.Status {
+		return nil
+	}
+	if err := setStatus(db, parent, status, actorID, now, "Статус рассчитан по подзадачам"); err != nil {
+		return err
+	}
+	return StatusChanged(db, parent.ID, actorID, now)
+}
+
+func rollUpStatus(current string, children []string) string {
+	if len(children) == 0 {
+		return current
+	}
+	var closed, done, started int
+	for _, status := range children {
+		switch status {
+		case "closed":
+			closed++
+			done++
+		case "resolved":
+			done++
+		case "new":
+			continue
+		}
+		started++
+	}
+
+	switch {
+	case closed == len(children):
+		return "closed"
+	case done == len(children):
+		return "resolved"
+	case current == "resolved" || current == "closed":
+		return "reopened"
+	case current == "new" && started > 0:
+		return "in_progress"
+	}
+	return current
+}
+
+func setStatus(db *gorm.DB, defect models.Defect, status string, actorID uint, now time.Time, comment string) error {
+	previous := defect.Status
+	defect.Status = status
+	sla.StampStatus(&defect, previous, now)
+	if err := db.Model(&defect).Select("status", "resolved_at", "closed_at").Updates(&defect).Error; err != nil {
+		return err
+	}
+	return audit.Record(db, "defects", defect.ID, "UPDATE", actorID, map[string]any{"status": previous}, map[string]any{"status": status}, comment)
+}
+
+// reachable проверяет, ведёт ли цепочка связей типа kind от from к to.
+func reachable(db *gorm.DB, kind string, from, to uint) (bool, error) {
+	var found bool
+	err := db.Raw(`
+		WITH RECURSIVE reach(id) AS (
+			SELECT ?::int
+			UNION
+			SELECT l.target_id FROM defect_links l JOIN reach r ON l.source_id = r.id WHERE l.type = ?
+		)
+		SELECT EXISTS (SELECT 1 FROM reach WHERE id = ?)
+	`, from, kind, to).Scan(&found).Error
+	return found, err
+}
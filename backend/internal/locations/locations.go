@@ -0,0 +1,104 @@
+package locations
+
+import (
+	"fmt"
+	"strings"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// levels задаёт порядок вложенности: площадка → здание → этаж → помещение.
+var levels = map[string]int{
+	"site":     0,
+	"building": 1,
+	"floor":    2,
+	"room":     3,
+}
+
+// CanContain сообщает, может ли узел вида parent содержать узел вида child.
+func CanContain(parent, child string) bool {
+	return levels[child] > levels[parent]
+}
+
+// ChildPath возвращает материализованный путь узла вида "/1/5/12/".
+func ChildPath(parent *models.Location, id uint) string {
+	prefix := "/"
+	if parent != nil {
+		prefix = parent.Path
+	}
+	return fmt.Sprintf("%s%d/", prefix, id)
+}
+
+// ProjectLocation проверяет, что узел принадлежит проекту.
+func ProjectLocation(db *gorm.DB, projectID uint, locationID *uint) (*models.Location, error) {
+	if locationID == nil {
+		return nil, nil
+	}
+
+	var location models.Location
+	if err := db.Where("id = ? AND project_id = ?", *locationID, projectID).First(&location).Error; err != nil {
+		return nil, fmt.Errorf("место не найдено в проекте дефекта")
+	}
+	return &location, nil
+}
+
+// FilterDefects оставляет дефекты, привязанные к узлу или любому из его потомков.
+func FilterDefects(query *gorm.DB, locationID *uint) *gorm.DB {
+	if locationID == nil {
+		return query
+	}
+	return query.Where(
+		"defects.location_id IN (SELECT id FROM locations WHERE path LIKE (SELECT path FROM locations WHERE id = ?) || '%')",
+		*locationID)
+}
+
+// Tree собирает плоский список узлов, упорядоченный по пути, в дерево.
+func Tree(flat []models.Location) []*models.Location {
+	nodes := make(map[uint]*models.Location, len(flat))
+	for i := range flat {
+		flat[i].Children = nil
+		nodes[flat[i].ID] = &flat[i]
+	}
+
+	roots := []*models.Location{}
+	for i := range flat {
+		node := &flat[i]
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// Move переносит узел под нового родителя и обновляет пути всего поддерева.
+func Move(tx *gorm.DB, location *models.Location, parent *models.Location) error {
+	if parent != nil && strings.HasPrefix(parent.Path, location.Path) {
+		return fmt.Errorf("нельзя переместить место внутрь самого себя")
+	}
+
+	oldPath := location.Path
+	newPath := ChildPath(parent, location.ID)
+	if oldPath == newPath {
+		return nil
+	}
+
+	if err := tx.Exec(
+		"UPDATE locations SET path = ? || SUBSTRING(path FROM ?) WHERE path LIKE ? || '%'",
+		newPath, len(oldPath)+1, oldPath).Error; err != nil {
+		return err
+	}
+
+	if parent != nil {
+		location.ParentID = &parent.ID
+	} else {
+		location.ParentID = nil
+	}
+	location.Path = newPath
+	return nil
+}
@@ -0,0 +1,138 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Attachment — вложение письма; ContentType по умолчанию application/octet-stream.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Sender отправляет письма. Без настроек SMTP используется LogSender, который только пишет письмо в лог.
+type Sender interface {
+	Send(message Message) error
+}
+
+// FromEnv выбирает отправителя по переменным окружения SMTP_HOST, SMTP_PORT, SMTP_USER,
+// SMTP_PASSWORD и SMTP_FROM.
+func FromEnv() Sender {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return LogSender{}
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USER")
+	}
+	return &SMTPSender{
+		Addr:     net.JoinHostPort(host, port),
+		Host:     host,
+		Username: os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	}
+}
+
+type SMTPSender struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTPSender) Send(message Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	if err := smtp.SendMail(s.Addr, auth, s.From, message.To, s.compose(message)); err != nil {
+		return fmt.Errorf("отправка письма: %w", err)
+	}
+	return nil
+}
+
+func (s *SMTPSender) compose(message Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(message.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	body := strings.ReplaceAll(message.Body, "\n", "\r\n")
+	if len(message.Attachments) == 0 {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		buf.WriteString(body)
+		return buf.Bytes()
+	}
+
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", parts.Boundary())
+	text, _ := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	text.Write([]byte(body))
+	for _, attachment := range message.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		writeBase64(part, attachment.Data)
+	}
+	parts.Close()
+	return buf.Bytes()
+}
+
+// writeBase64 пишет данные в base64 строками по 76 символов, как требует RFC 2045.
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// LogSender пишет письма в лог сервера — для разработки и стендов без почтового сервера.
+type LogSender struct{}
+
+func (LogSender) Send(message Message) error {
+	log.Printf("mail to %s: %s\n%s", strings.Join(message.To, ", "), message.Subject, message.Body)
+	for _, attachment := range message.Attachments {
+		log.Printf("mail attachment: %s (%d bytes)", attachment.Filename, len(attachment.Data))
+	}
+	return nil
+}
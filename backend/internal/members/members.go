@@ -0,0 +1,125 @@
+package members
+
+import (
+	"fmt"
+
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/organizations"
+
+	"gorm.io/gorm"
+)
+
+// Add добавляет пользователя в проект или меняет его роль в проекте. Роль в проекте должна
+// соответствовать глобальной роли пользователя, наблюдателем может быть любой. Исполнитель из организации
+// попадает только в проекты, к которым привлечена его организация.
+func Add(db *gorm.DB, projectID uint, user models.User, role string) (models.ProjectMember, error) {
+	globalRole, ok := models.ProjectMemberRoles[role]
+	if !ok {
+		return models.ProjectMember{}, fmt.Errorf("неизвестная роль в проекте %q", role)
+	}
+	if globalRole != "" && user.Role.Name != globalRole {
+		return models.ProjectMember{}, fmt.Errorf("роль «%s» в проекте доступна только пользователям с ролью «%s»", role, globalRole)
+	}
+	if user.Status == "inactive" {
+		return models.ProjectMember{}, fmt.Errorf("пользователь деактивирован")
+	}
+	if role == "assignee" && user.OrganizationID != nil && !organizations.Contracted(db, projectID, *user.OrganizationID) {
+		return models.ProjectMember{}, fmt.Errorf("организация исполнителя не привлечена к проекту")
+	}
+
+	var member models.ProjectMember
+	err := db.Where("project_id = ? AND user_id = ?", projectID, user.ID).First(&member).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return member, err
+	}
+	if member.ID != 0 && member.Role == "manager" && role != "manager" {
+		if err := handOverProject(db, projectID, user.ID); err != nil {
+			return member, err
+		}
+	}
+
+	member.ProjectID = projectID
+	member.UserID = user.ID
+	member.Role = role
+	if err := db.Save(&member).Error; err != nil {
+		return member, err
+	}
+	member.User = user
+	return member, nil
+}
+
+// Remove исключает пользователя из проекта. Последнего менеджера исключить нельзя; если уходит
+// ведущий менеджер, им становится другой менеджер проекта.
+func Remove(db *gorm.DB, projectID, userID uint) (models.ProjectMember, error) {
+	var member models.ProjectMember
+	if err := db.Where("project_id = ? AND user_id = ?", projectID, userID).First(&member).Error; err != nil {
+		return member, fmt.Errorf("пользователь не состоит в проекте")
+	}
+	if member.Role == "manager" {
+		if err := handOverProject(db, projectID, userID); err != nil {
+			return member, err
+		}
+	}
+	return member, db.Delete(&member).Error
+}
+
+// SoleManagerProjects возвращает число проектов, где пользователь — единственный менеджер.
+func SoleManagerProjects(db *gorm.DB, userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&models.ProjectMember{}).
+		Where("user_id = ? AND role = ?", userID, "manager").
+		Where("NOT EXISTS (SELECT 1 FROM project_members other WHERE other.project_id = project_members.project_id AND other.role = ? AND other.user_id <> ?)", "manager", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// HandOver передаёт другим менеджерам проекты, где пользователь — ведущий менеджер.
+// Вызывается перед деактивацией и сменой роли; проекты без других менеджеров должны быть отсеяны заранее.
+func HandOver(db *gorm.DB, userID uint) error {
+	var projectIDs []uint
+	if err := db.Model(&models.Project{}).Where("manager_id = ?", userID).Pluck("id", &projectIDs).Error; err != nil {
+		return err
+	}
+	for _, projectID := range projectIDs {
+		if err := handOverProject(db, projectID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropIncompatible удаляет участие в проектах, роль в котором не подходит под новую глобальную роль пользователя.
+func DropIncompatible(db *gorm.DB, userID uint, roleName string) error {
+	var incompatible []string
+	for role, globalRole := range models.ProjectMemberRoles {
+		if globalRole != "" && globalRole != roleName {
+			incompatible = append(incompatible, role)
+		}
+	}
+	if roleName != "Менеджер" {
+		if err := HandOver(db, userID); err != nil {
+			return err
+		}
+	}
+	return db.Where("user_id = ? AND role IN ?", userID, incompatible).Delete(&models.ProjectMember{}).Error
+}
+
+func handOverProject(db *gorm.DB, projectID, userID uint) error {
+	var project models.Project
+	if err := db.First(&project, projectID).Error; err != nil {
+		return err
+	}
+
+	var successor models.ProjectMember
+	err := db.Where("project_id = ? AND role = ? AND user_id <> ?", projectID, "manager", userID).Order("created_at, id").First(&successor).Error
+	if err == gorm.ErrRecordNotFound {
+		return fmt.Errorf("в проекте «%s» не останется менеджера, сначала добавьте другого", project.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if project.ManagerID != userID {
+		return nil
+	}
+	return db.Model(&project).Update("manager_id", successor.UserID).Error
+}
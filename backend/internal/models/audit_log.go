@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type AuditLog struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Table         string         `gorm:"column:table_name;type:varchar(255);not null" json:"table_name"`
+	RecordID      uint           `gorm:"not null" json:"record_id"`
+	Action        string         `gorm:"type:varchar(20);not null;check:action IN ('INSERT','UPDATE','DELETE')" json:"action"`
+	OldData       map[string]any `gorm:"type:jsonb;serializer:json" json:"old_data"`
+	NewData       map[string]any `gorm:"type:jsonb;serializer:json" json:"new_data"`
+	ChangedFields []string       `gorm:"type:jsonb;serializer:json" json:"changed_fields"`
+	Comment       string         `gorm:"type:text" json:"comment"`
+	Timestamp     time.Time      `gorm:"column:timestamp;autoCreateTime" json:"timestamp"`
+
+	UserID *uint `json:"user_id"`
+	User   *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type WorkCalendar struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Name      string `gorm:"type:varchar(100);not null" json:"name"`
+	Timezone  string `gorm:"type:varchar(50);not null;default:Europe/Moscow" json:"timezone"`
+	WorkStart string `gorm:"type:varchar(5);not null;default:09:00" json:"work_start"`
+	WorkEnd   string `gorm:"type:varchar(5);not null;default:18:00" json:"work_end"`
+	Weekends  []int  `gorm:"type:jsonb;serializer:json" json:"weekends"`
+
+	ProjectID *uint    `gorm:"unique" json:"project_id"`
+	Project   *Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+
+	Holidays []Holiday `gorm:"foreignKey:CalendarID" json:"holidays"`
+}
+
+type Holiday struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Date      time.Time `gorm:"type:date;not null" json:"date"`
+	Name      string    `gorm:"type:varchar(100);not null" json:"name"`
+	Working   bool      `gorm:"not null;default:false" json:"working"`
+	Recurring bool      `gorm:"not null;default:false" json:"recurring"`
+
+	CalendarID uint `gorm:"not null;index" json:"calendar_id"`
+}
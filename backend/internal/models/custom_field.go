@@ -0,0 +1,14 @@
+package models
+
+type CustomField struct {
+	ID       uint     `gorm:"primaryKey" json:"id"`
+	Key      string   `gorm:"type:varchar(50);not null;uniqueIndex:idx_custom_fields_project_key" json:"key"`
+	Name     string   `gorm:"type:varchar(100);not null" json:"name"`
+	Type     string   `gorm:"type:varchar(10);not null;check:type IN ('text','number','date','enum','user')" json:"type"`
+	Options  []string `gorm:"type:jsonb;serializer:json" json:"options"`
+	Required bool     `gorm:"not null;default:false" json:"required"`
+	Position int      `gorm:"not null;default:0" json:"position"`
+
+	ProjectID uint    `gorm:"not null;uniqueIndex:idx_custom_fields_project_key" json:"project_id"`
+	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Defect struct {
@@ -15,12 +17,48 @@ type Defect struct {
 	Attachments []string   `gorm:"type:jsonb;serializer:json" json:"attachments"`
 	DueDate     *time.Time `gorm:"type:timestamp with time zone" json:"duedate"`
 
+	CustomFields map[string]any `gorm:"type:jsonb;serializer:json" json:"custom_fields"`
+	PublicCode   *string        `gorm:"type:varchar(16);uniqueIndex" json:"public_code,omitempty"`
+
+	ResponseDueDate *time.Time `gorm:"type:timestamp with time zone" json:"response_duedate"`
+	AssignedAt      *time.Time `gorm:"type:timestamp with time zone" json:"assigned_at"`
+	ResolvedAt      *time.Time `gorm:"type:timestamp with time zone" json:"resolved_at"`
+	ClosedAt        *time.Time `gorm:"type:timestamp with time zone" json:"closed_at"`
+
+	// EstimateHours — оценка трудозатрат менеджером, фактические часы считаются по WorkLog.
+	EstimateHours *float64 `gorm:"type:numeric(6,2)" json:"estimate_hours"`
+
+	// Стоимость устранения. Если факт работ не задан менеджером, он складывается из стоимости
+	// работ в неотклонённых отчётах исполнителя.
+	EstimatedLabourCost *float64 `gorm:"type:numeric(12,2)" json:"estimated_labour_cost"`
+	ActualLabourCost    *float64 `gorm:"type:numeric(12,2)" json:"actual_labour_cost"`
+	ResponsibleParty    *string  `gorm:"type:varchar(30);check:responsible_party IN ('general_contractor','subcontractor','warranty')" json:"responsible_party"`
+
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
 	ProjectID uint    `gorm:"not null" json:"project_id"`
 	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"project"`
 
 	AuthorID uint `json:"author_id"`
 	Author   User `gorm:"foreignKey:AuthorID" json:"author"`
 
+	LocationID *uint     `gorm:"index" json:"location_id"`
+	Location   *Location `gorm:"foreignKey:LocationID;constraint:OnDelete:SET NULL" json:"location,omitempty"`
+
+	PlanID *uint      `gorm:"index" json:"plan_id"`
+	Plan   *FloorPlan `gorm:"foreignKey:PlanID;constraint:OnDelete:SET NULL" json:"-"`
+	PinX   *float64   `json:"pin_x"`
+	PinY   *float64   `json:"pin_y"`
+
+	// OrganizationID — организация, которой поручен дефект; исполнитель выбирается из её сотрудников.
+	OrganizationID *uint         `gorm:"index" json:"organization_id"`
+	Organization   *Organization `gorm:"foreignKey:OrganizationID;constraint:OnDelete:SET NULL" json:"organization,omitempty"`
+
 	AssigneeID *uint `gorm:"index" json:"assignee_id"`
 	Assignee   User  `gorm:"foreignKey:AssigneeID;constraint:OnDelete:SET NULL" json:"assignee"`
+
+	Labels []Label `gorm:"many2many:defect_labels;constraint:OnDelete:CASCADE" json:"labels"`
+
+	ImportJobID *uint      `gorm:"index" json:"import_job_id,omitempty"`
+	ImportJob   *ImportJob `gorm:"foreignKey:ImportJobID;constraint:OnDelete:SET NULL" json:"-"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+var LinkTypeNames = map[string]string{
+	"duplicate_of": "Дубликат",
+	"blocks":       "Блокирует",
+	"relates_to":   "Связан с",
+	"parent":       "Подзадача",
+}
+
+// DefectLink — связь «Source <Type> Target»: дубликат Target, блокирует Target, связан с Target
+// или является подзадачей Target.
+type DefectLink struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Type      string    `gorm:"type:varchar(20);not null;check:type IN ('duplicate_of','blocks','relates_to','parent')" json:"type"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	SourceID uint   `gorm:"not null" json:"source_id"`
+	Source   Defect `gorm:"foreignKey:SourceID;constraint:OnDelete:CASCADE" json:"source"`
+
+	TargetID uint   `gorm:"not null;index" json:"target_id"`
+	Target   Defect `gorm:"foreignKey:TargetID;constraint:OnDelete:CASCADE" json:"target"`
+
+	CreatedByID *uint `json:"created_by_id"`
+	CreatedBy   *User `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL" json:"-"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// DefectPhotoHash — перцептивный хеш (dHash) изображения из вложений дефекта.
+type DefectPhotoHash struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Path string `gorm:"type:varchar(255);not null;uniqueIndex:idx_defect_photo_hashes_defect_path" json:"path"`
+	Hash int64  `gorm:"not null" json:"hash"`
+
+	DefectID uint   `gorm:"not null;uniqueIndex:idx_defect_photo_hashes_defect_path" json:"defect_id"`
+	Defect   Defect `gorm:"foreignKey:DefectID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+var AssignmentKindNames = map[string]string{
+	"assign":   "Назначение",
+	"reassign": "Переназначение",
+}
+
+// DefectAssignment — запись истории назначений дефекта. DelegatedFromID заполняется, если выбранный
+// менеджером исполнитель отсутствовал и дефект ушёл его заместителю.
+type DefectAssignment struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Kind      string    `gorm:"type:varchar(20);not null;check:kind IN ('assign','reassign')" json:"kind"`
+	Reason    string    `gorm:"type:text;not null;default:''" json:"reason"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	DefectID uint   `gorm:"not null;index" json:"defect_id"`
+	Defect   Defect `gorm:"foreignKey:DefectID;constraint:OnDelete:CASCADE" json:"-"`
+
+	FromUserID      *uint `json:"from_user_id"`
+	FromUser        *User `gorm:"foreignKey:FromUserID;constraint:OnDelete:SET NULL" json:"from_user,omitempty"`
+	ToUserID        *uint `json:"to_user_id"`
+	ToUser          *User `gorm:"foreignKey:ToUserID;constraint:OnDelete:SET NULL" json:"to_user,omitempty"`
+	DelegatedFromID *uint `json:"delegated_from_id"`
+	DelegatedFrom   *User `gorm:"foreignKey:DelegatedFromID;constraint:OnDelete:SET NULL" json:"delegated_from,omitempty"`
+
+	FromOrganizationID *uint         `json:"from_organization_id"`
+	FromOrganization   *Organization `gorm:"foreignKey:FromOrganizationID;constraint:OnDelete:SET NULL" json:"from_organization,omitempty"`
+	ToOrganizationID   *uint         `json:"to_organization_id"`
+	ToOrganization     *Organization `gorm:"foreignKey:ToOrganizationID;constraint:OnDelete:SET NULL" json:"to_organization,omitempty"`
+
+	ActorID *uint `json:"actor_id"`
+	Actor   *User `gorm:"foreignKey:ActorID;constraint:OnDelete:SET NULL" json:"actor,omitempty"`
+}
+
+// Delegation — замещение пользователя заместителем с StartsOn по EndsOn включительно.
+type Delegation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	StartsOn  time.Time `gorm:"type:date;not null" json:"starts_on"`
+	EndsOn    time.Time `gorm:"type:date;not null" json:"ends_on"`
+	Comment   string    `gorm:"type:varchar(200);not null;default:''" json:"comment"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	UserID     uint `gorm:"not null" json:"user_id"`
+	User       User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user"`
+	DelegateID uint `gorm:"not null;index" json:"delegate_id"`
+	Delegate   User `gorm:"foreignKey:DelegateID;constraint:OnDelete:CASCADE" json:"delegate"`
+
+	CreatedByID *uint `json:"created_by_id"`
+	CreatedBy   *User `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL" json:"-"`
+}
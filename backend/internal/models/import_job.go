@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type ImportJob struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Filename     string     `gorm:"type:varchar(255);not null" json:"filename"`
+	Status       string     `gorm:"type:varchar(20);not null;default:applied;check:status IN ('applied','rolled_back')" json:"status"`
+	Total        int        `gorm:"not null" json:"total"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	RolledBackAt *time.Time `gorm:"type:timestamp with time zone" json:"rolled_back_at"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID" json:"user"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type LoginInput struct {
 	Email    string `json:"email" binding:"required,email"`
@@ -10,16 +13,28 @@ type LoginInput struct {
 type CreateUserInput struct {
 	FirstName  string `json:"first_name" binding:"required"`
 	LastName   string `json:"last_name" binding:"required"`
-	MiddleName string `json:"middle_name" binding:"required"`
+	MiddleName string `json:"middle_name"`
 	Email      string `json:"email" binding:"required,email"`
-	Password   string `json:"password" binding:"required"`
+	Password   string `json:"password"`
 	RoleID     uint   `json:"role_id" binding:"required"`
+
+	OrganizationID *uint `json:"organization_id"`
 }
 
 type CreateProjectInput struct {
 	Name        string `json:"name" binding:"required"`
 	ManagerID   uint   `json:"manager_id" binding:"required"`
 	Description string `json:"description"`
+	Status      string `json:"status" binding:"omitempty,oneof=planning active warranty"`
+}
+
+type UpdateProjectInput struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=200"`
+}
+
+type ProjectStatusInput struct {
+	Status string `json:"status" binding:"required,oneof=planning active warranty"`
 }
 
 type CreateDefectInput struct {
@@ -38,9 +53,113 @@ type EngineerEditDefectInput struct {
 }
 
 type ManagerEditDefectInput struct {
-	AssigneeID *uint      `json:"assignee_id"`
-	Status     string     `json:"status"`
-	DueDate    *time.Time `json:"duedate"`
+	OrganizationID *uint      `json:"organization_id"`
+	AssigneeID     *uint      `json:"assignee_id"`
+	Status         string     `json:"status"`
+	DueDate        *time.Time `json:"duedate"`
+	ClearDueDate   bool       `json:"clear_duedate"`
+	EstimateHours  *float64   `json:"estimate_hours" binding:"omitempty,gt=0,lte=9999"`
+
+	EstimatedLabourCost *float64 `json:"estimated_labour_cost" binding:"omitempty,gte=0"`
+	ActualLabourCost    *float64 `json:"actual_labour_cost" binding:"omitempty,gte=0"`
+	ResponsibleParty    *string  `json:"responsible_party" binding:"omitempty,oneof=general_contractor subcontractor warranty"`
+}
+
+type SLAPolicyInput struct {
+	Priority        string `json:"priority" binding:"required,oneof=low medium high critical"`
+	ResponseHours   int    `json:"response_hours" binding:"required,min=1"`
+	ResolutionHours int    `json:"resolution_hours" binding:"required,min=1"`
+	ExtensionHours  int    `json:"extension_hours" binding:"omitempty,min=1"`
+}
+
+type CalendarInput struct {
+	Name      string `json:"name" binding:"required"`
+	Timezone  string `json:"timezone"`
+	WorkStart string `json:"work_start" binding:"required"`
+	WorkEnd   string `json:"work_end" binding:"required"`
+	Weekends  []int  `json:"weekends" binding:"dive,min=0,max=6"`
+}
+
+type HolidayInput struct {
+	Date      string `json:"date" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+	Working   bool   `json:"working"`
+	Recurring bool   `json:"recurring"`
+}
+
+type LabelInput struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Color string `json:"color" binding:"omitempty,hexcolor,max=7"`
+	Kind  string `json:"kind" binding:"omitempty,oneof=trade cause other"`
+}
+
+type DefectLabelsInput struct {
+	LabelIDs []uint `json:"label_ids"`
+}
+
+type CustomFieldInput struct {
+	Key      string   `json:"key" binding:"required"`
+	Name     string   `json:"name" binding:"required,max=100"`
+	Type     string   `json:"type" binding:"required,oneof=text number date enum user"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+	Position int      `json:"position"`
+}
+
+type DefectCustomFieldsInput struct {
+	Values map[string]any `json:"values" binding:"required"`
+}
+
+type LocationInput struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	Kind     string `json:"kind" binding:"required,oneof=site building floor room"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// EditLocationInput — parent_id сырой, чтобы отличить пропущенное поле (место остаётся где было)
+// от явного null (место переносится в корень).
+type EditLocationInput struct {
+	Name     string          `json:"name" binding:"required,max=100"`
+	Kind     string          `json:"kind" binding:"required,oneof=site building floor room"`
+	ParentID json.RawMessage `json:"parent_id"`
+}
+
+type DefectLocationInput struct {
+	LocationID *uint `json:"location_id"`
+}
+
+type DefectPinInput struct {
+	PlanID *uint    `json:"plan_id"`
+	X      *float64 `json:"x" binding:"omitempty,min=0,max=1"`
+	Y      *float64 `json:"y" binding:"omitempty,min=0,max=1"`
+}
+
+type QRSheetInput struct {
+	DefectIDs  []uint `json:"defect_ids"`
+	LocationID *uint  `json:"location_id"`
+}
+
+type UpdateUserInput struct {
+	FirstName  string `json:"first_name" binding:"required,max=30"`
+	LastName   string `json:"last_name" binding:"required,max=30"`
+	MiddleName string `json:"middle_name" binding:"max=30"`
+	Email      string `json:"email" binding:"required,email,max=50"`
+
+	OrganizationID *uint `json:"organization_id"`
+}
+
+type ChangeRoleInput struct {
+	RoleID uint `json:"role_id" binding:"required"`
+}
+
+type AcceptInviteInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ProjectMemberInput struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=manager engineer assignee observer"`
 }
 
 type DefectAttachment struct {
@@ -73,3 +192,60 @@ type DefectDetail struct {
 	AuthorID   uint   `json:"author_id"`
 	AssigneeID *uint  `json:"assignee_id"`
 }
+
+type ReportScheduleInput struct {
+	Name       string   `json:"name" binding:"required,max=100"`
+	Frequency  string   `json:"frequency" binding:"required,oneof=daily weekly monthly"`
+	Format     string   `json:"format" binding:"required,oneof=pdf xlsx"`
+	ProjectID  *uint    `json:"project_id"`
+	Recipients []string `json:"recipients" binding:"dive,email,max=50"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+type DefectLinkInput struct {
+	Type     string `json:"type" binding:"required,oneof=duplicate_of blocks relates_to parent"`
+	TargetID uint   `json:"target_id" binding:"required"`
+}
+
+type WorkLogInput struct {
+	WorkDate    string  `json:"work_date" binding:"required"`
+	Hours       float64 `json:"hours" binding:"required,gt=0,lte=24"`
+	Description string  `json:"description" binding:"max=2000"`
+}
+
+type MaterialInput struct {
+	Name      string  `json:"name" binding:"required,max=200"`
+	Unit      string  `json:"unit" binding:"required,max=20"`
+	UnitPrice float64 `json:"unit_price" binding:"gte=0"`
+}
+
+type DefectMaterialInput struct {
+	MaterialID uint    `json:"material_id" binding:"required"`
+	Quantity   float64 `json:"quantity" binding:"required,gt=0"`
+	ReportID   *uint   `json:"report_id"`
+}
+
+type OrganizationInput struct {
+	Name    string  `json:"name" binding:"required,max=150"`
+	Kind    string  `json:"kind" binding:"omitempty,oneof=general_contractor subcontractor"`
+	TaxID   *string `json:"tax_id" binding:"omitempty,numeric,min=10,max=12"`
+	Contact *string `json:"contact" binding:"omitempty,max=200"`
+}
+
+type ProjectOrganizationInput struct {
+	OrganizationID uint `json:"organization_id" binding:"required"`
+}
+
+type ReassignDefectInput struct {
+	AssigneeID     uint   `json:"assignee_id" binding:"required"`
+	OrganizationID *uint  `json:"organization_id"`
+	Reason         string `json:"reason" binding:"required,min=3,max=1000"`
+}
+
+type DelegationInput struct {
+	UserID     *uint  `json:"user_id"`
+	DelegateID uint   `json:"delegate_id" binding:"required"`
+	StartsOn   string `json:"starts_on" binding:"required"`
+	EndsOn     string `json:"ends_on" binding:"required"`
+	Comment    string `json:"comment" binding:"max=200"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Invitation struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	TokenHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt  time.Time  `gorm:"type:timestamp with time zone;not null" json:"expires_at"`
+	AcceptedAt *time.Time `gorm:"type:timestamp with time zone" json:"accepted_at"`
+	RevokedAt  *time.Time `gorm:"type:timestamp with time zone" json:"revoked_at"`
+	SentAt     *time.Time `gorm:"type:timestamp with time zone" json:"sent_at"`
+	Status     string     `gorm:"-" json:"status"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user"`
+
+	CreatedByID uint `gorm:"not null" json:"created_by_id"`
+	CreatedBy   User `gorm:"foreignKey:CreatedByID" json:"-"`
+}
@@ -0,0 +1,22 @@
+package models
+
+type Label struct {
+	ID    uint   `gorm:"primaryKey" json:"id"`
+	Name  string `gorm:"type:varchar(50);not null;uniqueIndex:idx_labels_project_name" json:"name"`
+	Color string `gorm:"type:varchar(7);not null;default:#9ca3af" json:"color"`
+	Kind  string `gorm:"type:varchar(20);not null;default:other;check:kind IN ('trade','cause','other')" json:"kind"`
+
+	ProjectID uint    `gorm:"not null;uniqueIndex:idx_labels_project_name" json:"project_id"`
+	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type LabelStats struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	Kind      string `json:"kind"`
+	ProjectID uint   `json:"project_id"`
+	Total     int64  `json:"total"`
+	Open      int64  `json:"open"`
+	Closed    int64  `json:"closed"`
+}
@@ -0,0 +1,26 @@
+package models
+
+type Location struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"type:varchar(100);not null" json:"name"`
+	Kind string `gorm:"type:varchar(10);not null;check:kind IN ('site','building','floor','room')" json:"kind"`
+	Path string `gorm:"type:varchar(255);not null;index" json:"path"`
+
+	ProjectID uint    `gorm:"not null;index" json:"project_id"`
+	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+
+	ParentID *uint     `gorm:"index" json:"parent_id"`
+	Parent   *Location `gorm:"foreignKey:ParentID;constraint:OnDelete:RESTRICT" json:"-"`
+
+	Children []*Location `gorm:"-" json:"children,omitempty"`
+}
+
+type LocationStats struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	Kind         string `json:"kind"`
+	ParentID     *uint  `json:"parent_id"`
+	Total        int64  `json:"total"`
+	Open         int64  `json:"open"`
+	OpenCritical int64  `json:"open_critical"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+var ResponsiblePartyNames = map[string]string{
+	"general_contractor": "Генподрядчик",
+	"subcontractor":      "Субподрядчик",
+	"warranty":           "Гарантия",
+}
+
+// Material — позиция справочника материалов проекта с текущей ценой за единицу.
+type Material struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"type:varchar(200);not null;uniqueIndex:idx_materials_project_name" json:"name"`
+	Unit      string    `gorm:"type:varchar(20);not null" json:"unit"`
+	UnitPrice float64   `gorm:"type:numeric(12,2);not null" json:"unit_price"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	ProjectID uint    `gorm:"not null;uniqueIndex:idx_materials_project_name" json:"project_id"`
+	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// DefectMaterial — расход материала по дефекту. UnitPrice копируется из справочника при списании,
+// чтобы смена цены не меняла уже учтённые затраты. ReportID указывает отчёт, к которому относится расход.
+type DefectMaterial struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Quantity  float64   `gorm:"type:numeric(12,3);not null" json:"quantity"`
+	UnitPrice float64   `gorm:"type:numeric(12,2);not null" json:"unit_price"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	DefectID uint   `gorm:"not null;index" json:"defect_id"`
+	Defect   Defect `gorm:"foreignKey:DefectID;constraint:OnDelete:CASCADE" json:"-"`
+
+	MaterialID uint     `gorm:"not null;index" json:"material_id"`
+	Material   Material `gorm:"foreignKey:MaterialID" json:"material"`
+
+	ReportID *uint   `json:"report_id"`
+	Report   *Report `gorm:"foreignKey:ReportID;constraint:OnDelete:SET NULL" json:"-"`
+
+	CreatedByID *uint `json:"created_by_id"`
+	CreatedBy   *User `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL" json:"-"`
+}
+
+// CostReportRow — затраты на дефекты одной группы: проекта, места, причины или ответственной стороны.
+type CostReportRow struct {
+	Key             string  `json:"key"`
+	Name            string  `json:"name"`
+	Defects         int64   `json:"defects"`
+	EstimatedLabour float64 `json:"estimated_labour"`
+	ActualLabour    float64 `json:"actual_labour"`
+	Materials       float64 `json:"materials"`
+	Total           float64 `json:"total"`
+}
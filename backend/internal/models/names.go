@@ -0,0 +1,22 @@
+package models
+
+import "strings"
+
+var PriorityNames = map[string]string{
+	"low":      "Низкий",
+	"medium":   "Средний",
+	"high":     "Высокий",
+	"critical": "Критический",
+}
+
+var StatusNames = map[string]string{
+	"new":         "Новый",
+	"in_progress": "В работе",
+	"resolved":    "Устранён",
+	"closed":      "Закрыт",
+	"reopened":    "Переоткрыт",
+}
+
+func (u User) FullName() string {
+	return strings.TrimSpace(strings.Join([]string{u.LastName, u.FirstName, u.MiddleName}, " "))
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+var OrganizationKindNames = map[string]string{
+	"general_contractor": "Генподрядчик",
+	"subcontractor":      "Субподрядчик",
+}
+
+// Organization — подрядная организация, в которой работают пользователи, прежде всего исполнители.
+type Organization struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"type:varchar(150);not null" json:"name"`
+	Kind      string    `gorm:"type:varchar(20);not null;default:subcontractor;check:kind IN ('general_contractor','subcontractor')" json:"kind"`
+	TaxID     *string   `gorm:"type:varchar(12)" json:"tax_id"`
+	Contact   *string   `gorm:"type:varchar(200)" json:"contact"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// ProjectOrganization — организация, привлечённая к работам по проекту.
+type ProjectOrganization struct {
+	ProjectID uint      `gorm:"primaryKey" json:"project_id"`
+	Project   Project   `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	OrganizationID uint         `gorm:"primaryKey" json:"organization_id"`
+	Organization   Organization `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE" json:"organization"`
+}
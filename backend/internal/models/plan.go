@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type FloorPlan struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"type:varchar(100);not null" json:"name"`
+	FilePath    string    `gorm:"type:varchar(255);not null" json:"file_path"`
+	MimeType    string    `gorm:"type:varchar(50);not null" json:"mime_type"`
+	PreviewPath string    `gorm:"type:varchar(255)" json:"preview_path"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	ProjectID uint    `gorm:"not null;index" json:"project_id"`
+	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+
+	LocationID *uint     `gorm:"index" json:"location_id"`
+	Location   *Location `gorm:"foreignKey:LocationID;constraint:OnDelete:SET NULL" json:"-"`
+
+	UploadedByID uint `json:"uploaded_by_id"`
+	UploadedBy   User `gorm:"foreignKey:UploadedByID" json:"-"`
+}
+
+type PlanPin struct {
+	DefectID uint    `json:"defect_id"`
+	Title    string  `json:"title"`
+	Status   string  `json:"status"`
+	Priority string  `json:"priority"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+}
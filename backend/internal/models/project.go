@@ -1,10 +1,21 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Project struct {
 	ID          uint   `gorm:"primaryKey" json:"id"`
 	Name        string `gorm:"type:varchar(30); not null" json:"name"`
 	Description string `gorm:"type:varchar(200)" json:"description"`
 
-	ManagerID uint `gorm:"type:integer; not null; unique" json:"manager_id"`
+	Status     string         `gorm:"type:varchar(20);not null;default:active;check:status IN ('planning','active','warranty','archived')" json:"status"`
+	ArchivedAt *time.Time     `gorm:"type:timestamp with time zone" json:"archived_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	// ManagerID — ведущий менеджер проекта. Права менеджера дают записи project_members с ролью manager.
+	ManagerID uint `gorm:"type:integer; not null" json:"manager_id"`
 	Manager   User `gorm:"foreignKey:ManagerID" json:"manager"`
 }
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ProjectMemberRoles — роли участника в проекте и глобальные роли пользователей, которым они доступны.
+// Наблюдателем может быть пользователь с любой ролью.
+var ProjectMemberRoles = map[string]string{
+	"manager":  "Менеджер",
+	"engineer": "Инженер",
+	"assignee": "Исполнитель",
+	"observer": "",
+}
+
+type ProjectMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Role      string    `gorm:"type:varchar(20);not null;check:role IN ('manager','engineer','assignee','observer')" json:"role"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	ProjectID uint    `gorm:"not null;uniqueIndex:idx_project_members_project_user" json:"project_id"`
+	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+
+	UserID uint `gorm:"not null;uniqueIndex:idx_project_members_project_user;index" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user"`
+}
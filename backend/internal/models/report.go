@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Report struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
@@ -9,6 +13,9 @@ type Report struct {
 	Description string    `json:"description" gorm:"type:text"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	Status      string    `json:"status" gorm:"type:varchar(20);not null;check:status IN ('pending','approve','reject');default:pending"`
+	LabourCost  *float64  `json:"labour_cost" gorm:"type:numeric(12,2)"`
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	ProjectID uint    `json:"project_id" gorm:"not null"`
 	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"project"`
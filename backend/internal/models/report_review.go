@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type ReportReview struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Stage     string    `gorm:"type:varchar(20);not null;check:stage IN ('engineer','manager')" json:"stage"`
+	Decision  string    `gorm:"type:varchar(20);not null;check:decision IN ('approve','reject')" json:"decision"`
+	Comment   string    `gorm:"type:text" json:"comment"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	ReportID uint   `gorm:"not null;index" json:"report_id"`
+	Report   Report `gorm:"foreignKey:ReportID;constraint:OnDelete:CASCADE" json:"-"`
+
+	ReviewerID uint `gorm:"not null" json:"reviewer_id"`
+	Reviewer   User `gorm:"foreignKey:ReviewerID" json:"reviewer"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+var ScheduleFrequencyNames = map[string]string{
+	"daily":   "Ежедневная",
+	"weekly":  "Еженедельная",
+	"monthly": "Ежемесячная",
+}
+
+// ReportSchedule — расписание периодической сводки. Пустой ProjectID — сводка по всему портфелю.
+// Сводка отправляется владельцу расписания и на адреса из Recipients.
+type ReportSchedule struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Frequency  string     `gorm:"type:varchar(20);not null;check:frequency IN ('daily','weekly','monthly')" json:"frequency"`
+	Format     string     `gorm:"type:varchar(10);not null;default:pdf;check:format IN ('pdf','xlsx')" json:"format"`
+	Recipients []string   `gorm:"type:jsonb;serializer:json;not null" json:"recipients"`
+	Enabled    bool       `gorm:"not null;default:true" json:"enabled"`
+	NextRunAt  time.Time  `gorm:"type:timestamp with time zone;not null" json:"next_run_at"`
+	LastRunAt  *time.Time `gorm:"type:timestamp with time zone" json:"last_run_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+
+	ProjectID *uint    `gorm:"index" json:"project_id"`
+	Project   *Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"project,omitempty"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// ScheduledReport — запись истории: сводка за период [PeriodFrom, PeriodTo) и результат отправки.
+type ScheduledReport struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PeriodFrom time.Time `gorm:"type:timestamp with time zone;not null" json:"period_from"`
+	PeriodTo   time.Time `gorm:"type:timestamp with time zone;not null" json:"period_to"`
+	Filename   string    `gorm:"type:varchar(255);not null" json:"filename"`
+	Status     string    `gorm:"type:varchar(20);not null;check:status IN ('sent','failed')" json:"status"`
+	Error      string    `gorm:"type:text;not null" json:"error"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	ScheduleID uint           `gorm:"not null;index" json:"schedule_id"`
+	Schedule   ReportSchedule `gorm:"foreignKey:ScheduleID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// PeriodSummary — данные сводки за период для PDF и XLSX.
+type PeriodSummary struct {
+	Title string
+	From  time.Time
+	To    time.Time
+
+	Total    int64
+	ByStatus map[string]int64
+	Created  int64
+	Closed   int64
+	Overdue  []Defect
+
+	NewCritical []Defect
+	// Approved — окончательные приёмки отчётов менеджером за период.
+	Approved []ReportReview
+}
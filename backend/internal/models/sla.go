@@ -0,0 +1,25 @@
+package models
+
+type SLAPolicy struct {
+	ID              uint   `gorm:"primaryKey" json:"id"`
+	Priority        string `gorm:"type:varchar(20);not null;uniqueIndex:idx_sla_project_priority;check:priority IN ('low','medium','high','critical')" json:"priority"`
+	ResponseHours   int    `gorm:"not null" json:"response_hours"`
+	ResolutionHours int    `gorm:"not null" json:"resolution_hours"`
+	ExtensionHours  int    `gorm:"not null;default:72" json:"extension_hours"`
+
+	ProjectID uint    `gorm:"not null;uniqueIndex:idx_sla_project_priority" json:"project_id"`
+	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type SLACompliance struct {
+	ID              uint     `json:"id"`
+	Name            string   `json:"name"`
+	ResponseTotal   int64    `json:"response_total"`
+	ResponseMet     int64    `json:"response_met"`
+	ResponseRate    *float64 `gorm:"-" json:"response_rate"`
+	ResolutionTotal int64    `json:"resolution_total"`
+	ResolutionMet   int64    `json:"resolution_met"`
+	ResolutionRate  *float64 `gorm:"-" json:"resolution_rate"`
+
+	AvgResolutionHours float64 `gorm:"-" json:"avg_resolution_hours"`
+}
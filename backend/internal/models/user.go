@@ -1,13 +1,32 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type User struct {
 	ID         uint   `gorm:"primaryKey" json:"id"`
 	Email      string `gorm:"type:varchar(50);not null;unique" json:"email" binding:"required,email"`
-	Password   string `gorm:"type:varchar(200);not null" json:"password" binding:"required"`
+	Password   string `gorm:"type:varchar(200);not null" json:"-" binding:"required"`
 	FirstName  string `gorm:"type:varchar(30);not null" json:"first_name" binding:"required"`
 	LastName   string `gorm:"type:varchar(30);not null" json:"last_name" binding:"required"`
-	MiddleName string `gorm:"type:varchar(30)" json:"middle_name" binding:"required"`
+	MiddleName string `gorm:"type:varchar(30)" json:"middle_name"`
+
+	Status        string         `gorm:"type:varchar(20);not null;default:active;check:status IN ('active','inactive','pending')" json:"status"`
+	DeactivatedAt *time.Time     `gorm:"type:timestamp with time zone" json:"deactivated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
 	RoleID uint `gorm:"not null" json:"role_id"`
 	Role   Role `gorm:"foreignKey:RoleID" json:"role"`
+
+	OrganizationID *uint         `gorm:"index" json:"organization_id"`
+	Organization   *Organization `gorm:"foreignKey:OrganizationID;constraint:OnDelete:SET NULL" json:"organization,omitempty"`
+}
+
+// WithDeleted — условие для Preload: автор, исполнитель или менеджер остаётся виден в истории
+// и после того, как пользователь перемещён в корзину.
+func WithDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// WorkLog — запись о работе исполнителя по дефекту за день.
+type WorkLog struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	WorkDate    time.Time `gorm:"type:date;not null" json:"work_date"`
+	Hours       float64   `gorm:"type:numeric(5,2);not null;check:hours > 0 AND hours <= 24" json:"hours"`
+	Description string    `gorm:"type:text;not null" json:"description"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	DefectID uint   `gorm:"not null;index" json:"defect_id"`
+	Defect   Defect `gorm:"foreignKey:DefectID;constraint:OnDelete:CASCADE" json:"-"`
+
+	UserID uint `gorm:"not null" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID" json:"user"`
+}
+
+// TimePeriodNames — шаги группировки отчёта по трудозатратам.
+var TimePeriodNames = map[string]string{
+	"day":   "День",
+	"week":  "Неделя",
+	"month": "Месяц",
+}
+
+// TimeReportRow — трудозатраты одного исполнителя по проекту за период группировки.
+type TimeReportRow struct {
+	Period      time.Time `json:"period"`
+	ProjectID   uint      `json:"project_id"`
+	ProjectName string    `json:"project_name"`
+	UserID      uint      `json:"user_id"`
+	UserName    string    `json:"user_name"`
+	Hours       float64   `json:"hours"`
+	Entries     int64     `json:"entries"`
+	Defects     int64     `json:"defects"`
+}
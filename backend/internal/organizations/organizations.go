@@ -0,0 +1,117 @@
+package organizations
+
+import (
+	"fmt"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Contracted проверяет, что организация привлечена к работам по проекту.
+func Contracted(db *gorm.DB, projectID, organizationID uint) bool {
+	var count int64
+	if err := db.Model(&models.ProjectOrganization{}).Where("project_id = ? AND organization_id = ?", projectID, organizationID).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// Exists проверяет ссылку на организацию из формы; пустая ссылка допустима.
+func Exists(db *gorm.DB, organizationID *uint) error {
+	if organizationID == nil {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.Organization{}).Where("id = ?", *organizationID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("организация не найдена")
+	}
+	return nil
+}
+
+// Assign определяет организацию дефекта при назначении. organizationID — организация из запроса,
+// assignee — впервые назначаемый исполнитель. Организация должна быть привлечена к проекту, исполнитель —
+// состоять в ней. Если организация не выбрана, дефект переходит к организации исполнителя.
+// Сменить организацию после назначения исполнителя нельзя.
+func Assign(db *gorm.DB, defect *models.Defect, organizationID *uint, assignee *models.User) error {
+	organization := defect.OrganizationID
+	if organizationID != nil && (organization == nil || *organization != *organizationID) {
+		if !Contracted(db, defect.ProjectID, *organizationID) {
+			return fmt.Errorf("организация не привлечена к проекту")
+		}
+		if defect.AssigneeID != nil {
+			return fmt.Errorf("нельзя сменить организацию: исполнитель уже назначен")
+		}
+		organization = organizationID
+	}
+
+	if assignee != nil {
+		switch {
+		case organization != nil:
+			if assignee.OrganizationID == nil || *assignee.OrganizationID != *organization {
+				return fmt.Errorf("исполнитель не состоит в организации, которой поручен дефект")
+			}
+		case assignee.OrganizationID != nil && Contracted(db, defect.ProjectID, *assignee.OrganizationID):
+			organization = assignee.OrganizationID
+		}
+	}
+
+	defect.OrganizationID = organization
+	return nil
+}
+
+// Withdraw снимает организацию с проекта, если по проекту за ней не осталось незакрытых дефектов.
+func Withdraw(db *gorm.DB, projectID, organizationID uint) error {
+	var open int64
+	if err := db.Model(&models.Defect{}).
+		Where("project_id = ? AND organization_id = ? AND status <> ?", projectID, organizationID, "closed").
+		Count(&open).Error; err != nil {
+		return err
+	}
+	if open > 0 {
+		return fmt.Errorf("за организацией остаются незакрытые дефекты проекта: %d", open)
+	}
+	result := db.Where("project_id = ? AND organization_id = ?", projectID, organizationID).Delete(&models.ProjectOrganization{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("организация не привлечена к проекту")
+	}
+	return nil
+}
+
+// InUse возвращает причину, по которой организацию нельзя удалить, или пустую строку.
+func InUse(db *gorm.DB, organizationID uint) (string, error) {
+	checks := []struct {
+		model   any
+		message string
+	}{
+		{&models.User{}, "в организации есть пользователи"},
+		{&models.ProjectOrganization{}, "организация привлечена к проектам"},
+		{&models.Defect{}, "организации поручены дефекты"},
+	}
+	for _, check := range checks {
+		var count int64
+		if err := db.Model(check.model).Unscoped().Where("organization_id = ?", organizationID).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return check.message, nil
+		}
+	}
+	return "", nil
+}
+
+// Snapshot — поля организации для журнала изменений.
+func Snapshot(organization models.Organization) map[string]any {
+	return map[string]any{
+		"name":    organization.Name,
+		"kind":    organization.Kind,
+		"tax_id":  organization.TaxID,
+		"contact": organization.Contact,
+	}
+}
@@ -0,0 +1,167 @@
+package pdfdoc
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	dateFormat     = "02.01.2006"
+	dateTimeFormat = "02.01.2006 15:04"
+)
+
+func heading(pdf *fpdf.Fpdf, text string) {
+	pdf.Ln(3)
+	pdf.SetFont(Font, "B", 12)
+	pdf.CellFormat(0, 7, text, "B", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	pdf.SetFont(Font, "", 10)
+}
+
+// field выводит строку «название — значение», значение переносится по словам.
+func field(pdf *fpdf.Fpdf, label, value string) {
+	if value == "" {
+		value = "—"
+	}
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+
+	pdf.SetFont(Font, "B", 10)
+	pdf.CellFormat(50, 6, label, "", 0, "L", false, 0, "")
+	pdf.SetFont(Font, "", 10)
+	pdf.MultiCell(pageWidth-left-right-50, 6, value, "", "L", false)
+}
+
+type column struct {
+	title string
+	width float64
+	align string
+}
+
+func tableHeader(pdf *fpdf.Fpdf, columns []column) {
+	pdf.SetFont(Font, "B", 9)
+	pdf.SetFillColor(229, 231, 235)
+	for _, col := range columns {
+		pdf.CellFormat(col.width, 7, col.title, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont(Font, "", 9)
+}
+
+// tableRow выводит строку таблицы, высота подбирается по самой длинной ячейке.
+func tableRow(pdf *fpdf.Fpdf, columns []column, values []string) {
+	const lineHeight = 5.0
+
+	lines := 1
+	for i, col := range columns {
+		if n := len(pdf.SplitText(values[i], col.width-2)); n > lines {
+			lines = n
+		}
+	}
+	height := float64(lines) * lineHeight
+
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	if pdf.GetY()+height > pageHeight-bottom {
+		pdf.AddPage()
+		tableHeader(pdf, columns)
+	}
+
+	x, y := pdf.GetXY()
+	for i, col := range columns {
+		pdf.Rect(x, y, col.width, height, "D")
+		pdf.SetXY(x+1, y)
+		pdf.MultiCell(col.width-2, lineHeight, values[i], "", col.align, false)
+		x += col.width
+	}
+	pdf.SetXY(pdf.GetX(), y+height)
+	left, _, _, _ := pdf.GetMargins()
+	pdf.SetX(left)
+}
+
+func isImage(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// photos выкладывает изображения по два в ряд; недоступные файлы пропускаются.
+func photos(pdf *fpdf.Fpdf, caption string, paths []string) {
+	var images []string
+	for _, path := range paths {
+		if isImage(path) {
+			images = append(images, path)
+		}
+	}
+	if len(images) == 0 {
+		return
+	}
+
+	pdf.SetFont(Font, "B", 10)
+	pdf.CellFormat(0, 6, caption, "", 1, "L", false, 0, "")
+	pdf.SetFont(Font, "", 10)
+
+	left, _, right, bottom := pdf.GetMargins()
+	pageWidth, pageHeight := pdf.GetPageSize()
+	const gap = 5.0
+	width := (pageWidth - left - right - gap) / 2
+
+	rowHeight := 0.0
+	for i, path := range images {
+		options := fpdf.ImageOptions{ReadDpi: true}
+		info := pdf.RegisterImageOptions("."+path, options)
+		if !pdf.Ok() {
+			// Повреждённый или удалённый файл не должен ломать весь документ.
+			pdf.ClearError()
+			continue
+		}
+		height := width * info.Height() / info.Width()
+		height = min(height, 110)
+
+		col := i % 2
+		if col == 0 {
+			if rowHeight > 0 {
+				pdf.SetY(pdf.GetY() + rowHeight + gap)
+			}
+			rowHeight = 0
+			if pdf.GetY()+height > pageHeight-bottom {
+				pdf.AddPage()
+			}
+		}
+
+		x := left + float64(col)*(width+gap)
+		pdf.ImageOptions("."+path, x, pdf.GetY(), 0, height, false, options, 0, "")
+		rowHeight = max(rowHeight, height)
+	}
+	pdf.SetY(pdf.GetY() + rowHeight + gap)
+}
+
+// signatures выводит блок подписей: должность, подпись, ФИО и дата.
+func signatures(pdf *fpdf.Fpdf, roles [][2]string) {
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	if pdf.GetY()+float64(len(roles))*16+10 > pageHeight-bottom {
+		pdf.AddPage()
+	}
+
+	heading(pdf, "Подписи")
+	for _, role := range roles {
+		pdf.SetFont(Font, "", 10)
+		pdf.CellFormat(55, 12, role[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(40, 12, "", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(5, 12, "", "", 0, "L", false, 0, "")
+		pdf.CellFormat(55, 12, role[1], "B", 0, "C", false, 0, "")
+		pdf.CellFormat(5, 12, "", "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 12, "«___» ________ 20__ г.", "", 1, "R", false, 0, "")
+
+		pdf.SetFont(Font, "", 7)
+		pdf.CellFormat(55, 4, "", "", 0, "L", false, 0, "")
+		pdf.CellFormat(40, 4, "подпись", "", 0, "C", false, 0, "")
+		pdf.CellFormat(5, 4, "", "", 0, "L", false, 0, "")
+		pdf.CellFormat(55, 4, "ФИО", "", 1, "C", false, 0, "")
+	}
+}
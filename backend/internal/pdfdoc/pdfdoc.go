@@ -0,0 +1,20 @@
+package pdfdoc
+
+import (
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Font — семейство шрифта с кириллицей, встроенное в бинарник, чтобы PDF не зависел от шрифтов системы.
+const Font = "Go"
+
+// New создаёт документ A4 в миллиметрах с зарегистрированными шрифтами.
+func New(orientation string) *fpdf.Fpdf {
+	pdf := fpdf.New(orientation, "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(Font, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(Font, "B", gobold.TTF)
+	pdf.SetFont(Font, "", 10)
+	pdf.SetAutoPageBreak(true, 15)
+	return pdf
+}
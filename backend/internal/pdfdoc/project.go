@@ -0,0 +1,82 @@
+package pdfdoc
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// ClosedDefectsSummary формирует сводку по дефектам проекта, закрытым за период [from, to).
+func ClosedDefectsSummary(project models.Project, defects []models.Defect, from, to time.Time) *fpdf.Fpdf {
+	pdf := New("L")
+	pdf.SetTitle(fmt.Sprintf("Закрытые дефекты — %s", project.Name), true)
+	pdf.AddPage()
+
+	pdf.SetFont(Font, "B", 14)
+	pdf.CellFormat(0, 8, "Сводка закрытых дефектов", "", 1, "C", false, 0, "")
+	pdf.SetFont(Font, "", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Проект: %s", project.Name), "", 1, "C", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Период: %s — %s", from.Format(dateFormat), to.AddDate(0, 0, -1).Format(dateFormat)), "", 1, "C", false, 0, "")
+
+	heading(pdf, "Итоги")
+	byPriority := map[string]int{}
+	overdue := 0
+	for _, defect := range defects {
+		byPriority[defect.Priority]++
+		if defect.DueDate != nil && defect.ClosedAt != nil && defect.ClosedAt.After(*defect.DueDate) {
+			overdue++
+		}
+	}
+	field(pdf, "Закрыто дефектов", strconv.Itoa(len(defects)))
+	for _, priority := range []string{"critical", "high", "medium", "low"} {
+		field(pdf, models.PriorityNames[priority], strconv.Itoa(byPriority[priority]))
+	}
+	field(pdf, "Закрыто с нарушением срока", strconv.Itoa(overdue))
+	field(pdf, "Менеджер проекта", project.Manager.FullName())
+
+	heading(pdf, "Перечень дефектов")
+	columns := []column{
+		{"№", 14, "R"},
+		{"Дефект", 78, "L"},
+		{"Место", 35, "L"},
+		{"Приоритет", 25, "L"},
+		{"Исполнитель", 45, "L"},
+		{"Выявлен", 26, "L"},
+		{"Срок", 26, "L"},
+		{"Закрыт", 28, "L"},
+	}
+	tableHeader(pdf, columns)
+	for _, defect := range defects {
+		location := ""
+		if defect.Location != nil {
+			location = defect.Location.Name
+		}
+		tableRow(pdf, columns, []string{
+			strconv.Itoa(int(defect.ID)),
+			defect.Title,
+			location,
+			models.PriorityNames[defect.Priority],
+			defect.Assignee.FullName(),
+			defect.CreatedAt.Format(dateFormat),
+			formatDate(defect.DueDate),
+			formatDate(defect.ClosedAt),
+		})
+	}
+
+	signatures(pdf, [][2]string{
+		{"Менеджер проекта", project.Manager.FullName()},
+	})
+
+	return pdf
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(dateFormat)
+}
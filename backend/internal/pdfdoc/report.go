@@ -0,0 +1,110 @@
+package pdfdoc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"systemacontrolya/internal/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+type AcceptanceData struct {
+	Report  models.Report
+	Defect  models.Defect
+	Manager models.User
+	Reviews []models.ReportReview
+}
+
+var stageNames = map[string]string{
+	"engineer": "Инженер",
+	"manager":  "Менеджер",
+}
+
+var decisionNames = map[string]string{
+	"approve": "Принято",
+	"reject":  "Отклонено",
+}
+
+// AcceptanceCertificate формирует акт приёмки работ по отчёту исполнителя.
+func AcceptanceCertificate(data AcceptanceData) *fpdf.Fpdf {
+	report, defect := data.Report, data.Defect
+
+	pdf := New("P")
+	pdf.SetTitle(fmt.Sprintf("Акт приёмки № %d", report.ID), true)
+	pdf.AddPage()
+
+	pdf.SetFont(Font, "B", 14)
+	pdf.MultiCell(0, 8, fmt.Sprintf("АКТ № %d\nприёмки работ по устранению дефекта", report.ID), "", "C", false)
+	pdf.SetFont(Font, "", 10)
+	pdf.CellFormat(0, 6, "Дата формирования: "+time.Now().Format(dateFormat), "", 1, "R", false, 0, "")
+
+	heading(pdf, "Сведения о дефекте")
+	field(pdf, "Проект", report.Project.Name)
+	if defect.Location != nil {
+		field(pdf, "Место", defect.Location.Name)
+	}
+	field(pdf, "Дефект", fmt.Sprintf("№ %d. %s", defect.ID, defect.Title))
+	field(pdf, "Описание", defect.Description)
+	field(pdf, "Приоритет", models.PriorityNames[defect.Priority])
+	field(pdf, "Статус", models.StatusNames[defect.Status])
+	field(pdf, "Зарегистрирован", fmt.Sprintf("%s, %s", defect.CreatedAt.Format(dateTimeFormat), defect.Author.FullName()))
+	field(pdf, "Срок устранения", formatTime(defect.DueDate))
+	field(pdf, "Закрыт", formatTime(defect.ClosedAt))
+	if len(defect.Labels) > 0 {
+		names := make([]string, 0, len(defect.Labels))
+		for _, label := range defect.Labels {
+			names = append(names, label.Name)
+		}
+		field(pdf, "Метки", strings.Join(names, ", "))
+	}
+
+	heading(pdf, "Выполненные работы")
+	field(pdf, "Исполнитель", report.User.FullName())
+	field(pdf, "Отчёт", report.Title)
+	field(pdf, "Дата отчёта", report.CreatedAt.Format(dateTimeFormat))
+	field(pdf, "Описание работ", report.Description)
+
+	heading(pdf, "Решения проверяющих")
+	if len(data.Reviews) == 0 {
+		pdf.CellFormat(0, 6, "Решений по отчёту нет", "", 1, "L", false, 0, "")
+	} else {
+		columns := []column{
+			{"Дата", 32, "L"},
+			{"Этап", 25, "L"},
+			{"Проверяющий", 50, "L"},
+			{"Решение", 25, "L"},
+			{"Комментарий", 48, "L"},
+		}
+		tableHeader(pdf, columns)
+		for _, review := range data.Reviews {
+			tableRow(pdf, columns, []string{
+				review.CreatedAt.Format(dateTimeFormat),
+				stageNames[review.Stage],
+				review.Reviewer.FullName(),
+				decisionNames[review.Decision],
+				review.Comment,
+			})
+		}
+	}
+
+	heading(pdf, "Фотоматериалы")
+	photos(pdf, "До устранения", defect.Attachments)
+	photos(pdf, "После устранения", report.FilePaths)
+
+	signatures(pdf, [][2]string{
+		{"Исполнитель", report.User.FullName()},
+		{"Инженер технадзора", defect.Author.FullName()},
+		{"Менеджер проекта", data.Manager.FullName()},
+	})
+
+	return pdf
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(dateTimeFormat)
+}
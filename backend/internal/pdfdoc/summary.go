@@ -0,0 +1,107 @@
+package pdfdoc
+
+import (
+	"fmt"
+	"strconv"
+
+	"systemacontrolya/internal/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// PeriodSummary формирует сводку для руководителя: ключевые показатели, новые критические дефекты,
+// просроченные дефекты и принятые отчёты за период [From, To).
+func PeriodSummary(summary models.PeriodSummary) *fpdf.Fpdf {
+	pdf := New("L")
+	pdf.SetTitle(fmt.Sprintf("Сводка — %s", summary.Title), true)
+	pdf.AddPage()
+
+	pdf.SetFont(Font, "B", 14)
+	pdf.CellFormat(0, 8, "Сводка по дефектам", "", 1, "C", false, 0, "")
+	pdf.SetFont(Font, "", 10)
+	pdf.CellFormat(0, 6, summary.Title, "", 1, "C", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Период: %s — %s", summary.From.Format(dateTimeFormat), summary.To.Format(dateTimeFormat)), "", 1, "C", false, 0, "")
+
+	heading(pdf, "Ключевые показатели")
+	field(pdf, "Всего дефектов", strconv.FormatInt(summary.Total, 10))
+	for _, status := range []string{"new", "in_progress", "reopened", "resolved", "closed"} {
+		field(pdf, models.StatusNames[status], strconv.FormatInt(summary.ByStatus[status], 10))
+	}
+	field(pdf, "Выявлено за период", strconv.FormatInt(summary.Created, 10))
+	field(pdf, "Закрыто за период", strconv.FormatInt(summary.Closed, 10))
+	field(pdf, "Просрочено сейчас", strconv.Itoa(len(summary.Overdue)))
+	field(pdf, "Принято отчётов", strconv.Itoa(len(summary.Approved)))
+
+	defectColumns := []column{
+		{"№", 14, "R"},
+		{"Дефект", 80, "L"},
+		{"Проект", 40, "L"},
+		{"Место", 35, "L"},
+		{"Исполнитель", 45, "L"},
+		{"Выявлен", 26, "L"},
+		{"Срок", 27, "L"},
+	}
+	defectRow := func(defect models.Defect) []string {
+		location := ""
+		if defect.Location != nil {
+			location = defect.Location.Name
+		}
+		return []string{
+			strconv.Itoa(int(defect.ID)),
+			defect.Title,
+			defect.Project.Name,
+			location,
+			defect.Assignee.FullName(),
+			defect.CreatedAt.Format(dateFormat),
+			formatDate(defect.DueDate),
+		}
+	}
+
+	heading(pdf, "Новые критические дефекты")
+	if len(summary.NewCritical) == 0 {
+		pdf.CellFormat(0, 6, "Нет", "", 1, "L", false, 0, "")
+	} else {
+		tableHeader(pdf, defectColumns)
+		for _, defect := range summary.NewCritical {
+			tableRow(pdf, defectColumns, defectRow(defect))
+		}
+	}
+
+	heading(pdf, "Просроченные дефекты")
+	if len(summary.Overdue) == 0 {
+		pdf.CellFormat(0, 6, "Нет", "", 1, "L", false, 0, "")
+	} else {
+		tableHeader(pdf, defectColumns)
+		for _, defect := range summary.Overdue {
+			tableRow(pdf, defectColumns, defectRow(defect))
+		}
+	}
+
+	heading(pdf, "Принятые отчёты")
+	if len(summary.Approved) == 0 {
+		pdf.CellFormat(0, 6, "Нет", "", 1, "L", false, 0, "")
+	} else {
+		columns := []column{
+			{"№", 14, "R"},
+			{"Отчёт", 70, "L"},
+			{"Дефект", 70, "L"},
+			{"Проект", 40, "L"},
+			{"Исполнитель", 45, "L"},
+			{"Принят", 28, "L"},
+		}
+		tableHeader(pdf, columns)
+		for _, review := range summary.Approved {
+			report := review.Report
+			tableRow(pdf, columns, []string{
+				strconv.Itoa(int(report.ID)),
+				report.Title,
+				fmt.Sprintf("№ %d. %s", report.Defect.ID, report.Defect.Title),
+				report.Project.Name,
+				report.User.FullName(),
+				review.CreatedAt.Format(dateFormat),
+			})
+		}
+	}
+
+	return pdf
+}
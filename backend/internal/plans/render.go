@@ -0,0 +1,112 @@
+package plans
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strconv"
+
+	"systemacontrolya/internal/models"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+var priorityColors = map[string]color.RGBA{
+	"critical": {R: 220, G: 38, B: 38, A: 255},
+	"high":     {R: 234, G: 88, B: 12, A: 255},
+	"medium":   {R: 234, G: 179, B: 8, A: 255},
+	"low":      {R: 37, G: 99, B: 235, A: 255},
+}
+
+var closedColor = color.RGBA{R: 156, G: 163, B: 175, A: 255}
+
+// ImageTypes — форматы, которые можно использовать как подложку для отметок.
+var ImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+}
+
+func DecodeFile(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать изображение плана: %w", err)
+	}
+	return img, nil
+}
+
+func DecodeConfig(path string) (image.Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer file.Close()
+
+	cfg, _, err := image.DecodeConfig(file)
+	return cfg, err
+}
+
+// Annotate рисует поверх плана отметки дефектов с номером, цвет отметки зависит от приоритета.
+func Annotate(plan image.Image, pins []models.PlanPin) *image.RGBA {
+	bounds := plan.Bounds()
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, plan, bounds.Min, draw.Src)
+
+	radius := min(bounds.Dx(), bounds.Dy()) / 80
+	radius = max(radius, 7)
+
+	for _, pin := range pins {
+		center := image.Point{
+			X: bounds.Min.X + int(pin.X*float64(bounds.Dx())),
+			Y: bounds.Min.Y + int(pin.Y*float64(bounds.Dy())),
+		}
+
+		fill, ok := priorityColors[pin.Priority]
+		if !ok || pin.Status == "closed" {
+			fill = closedColor
+		}
+
+		drawCircle(canvas, center, radius+2, color.White)
+		drawCircle(canvas, center, radius, fill)
+		drawLabel(canvas, image.Point{X: center.X + radius + 3, Y: center.Y + 4}, "#"+strconv.Itoa(int(pin.DefectID)))
+	}
+
+	return canvas
+}
+
+func drawCircle(canvas *image.RGBA, center image.Point, radius int, c color.Color) {
+	for y := -radius; y <= radius; y++ {
+		for x := -radius; x <= radius; x++ {
+			if x*x+y*y <= radius*radius {
+				canvas.Set(center.X+x, center.Y+y, c)
+			}
+		}
+	}
+}
+
+func drawLabel(canvas *image.RGBA, at image.Point, text string) {
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, text).Ceil()
+
+	background := image.Rect(at.X-2, at.Y-11, at.X+width+2, at.Y+4)
+	draw.Draw(canvas, background, image.NewUniform(color.RGBA{R: 255, G: 255, B: 255, A: 220}), image.Point{}, draw.Over)
+
+	drawer := &font.Drawer{
+		Dst:  canvas,
+		Src:  image.NewUniform(color.Black),
+		Face: face,
+		Dot:  fixed.P(at.X, at.Y),
+	}
+	drawer.DrawString(text)
+}
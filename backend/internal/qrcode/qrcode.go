@@ -0,0 +1,81 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+// quietZone — обязательное поле вокруг кода в модулях, без него сканеры читают код хуже.
+const quietZone = 4
+
+func encode(content string) (barcode.Barcode, error) {
+	return qr.Encode(content, qr.M, qr.Auto)
+}
+
+// PNG рисует код размером не меньше size пикселей.
+func PNG(content string, size int) ([]byte, error) {
+	code, err := encode(content)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := code.Bounds().Dx()
+	scale := max(size/(modules+2*quietZone), 1)
+	side := (modules + 2*quietZone) * scale
+
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	for y := 0; y < modules; y++ {
+		for x := 0; x < modules; x++ {
+			if code.At(x, y) != color.Black {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray((x+quietZone)*scale+dx, (y+quietZone)*scale+dy, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG рисует код векторно, один модуль — одна единица viewBox.
+func SVG(content string) ([]byte, error) {
+	code, err := encode(content)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := code.Bounds().Dx()
+	side := modules + 2*quietZone
+
+	var path strings.Builder
+	for y := 0; y < modules; y++ {
+		for x := 0; x < modules; x++ {
+			if code.At(x, y) == color.Black {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, side, side)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/>`, side, side)
+	fmt.Fprintf(&buf, `<path d="%s" fill="#000"/></svg>`, path.String())
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,59 @@
+package schedules
+
+import (
+	"log"
+	"time"
+
+	"systemacontrolya/internal/mailer"
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// checkInterval — как часто проверяются наступившие расписания.
+const checkInterval = 5 * time.Minute
+
+// Run периодически отправляет сводки по наступившим расписаниям.
+func Run(db *gorm.DB, sender mailer.Sender) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		if err := RunDue(db, sender, time.Now()); err != nil {
+			log.Printf("report schedules: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// RunDue отправляет сводки по расписаниям, срок которых наступил к now. Сводка строится за период,
+// закончившийся в next_run_at; пропущенные периоды (сервер был остановлен) не догоняются.
+// Расписание сначала переносится на следующий срок условным UPDATE, поэтому при нескольких
+// экземплярах сервера сводку отправит только один.
+func RunDue(db *gorm.DB, sender mailer.Sender, now time.Time) error {
+	var due []models.ReportSchedule
+	if err := db.Preload("User").
+		Where("enabled AND next_run_at <= ?", now).
+		Where("project_id IS NULL OR project_id IN (SELECT id FROM projects WHERE deleted_at IS NULL)").
+		Where("user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)").
+		Find(&due).Error; err != nil {
+		return err
+	}
+
+	for _, schedule := range due {
+		result := db.Model(&models.ReportSchedule{}).
+			Where("id = ? AND next_run_at = ?", schedule.ID, schedule.NextRunAt).
+			Updates(map[string]any{"next_run_at": Next(schedule.Frequency, now), "last_run_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		to := schedule.NextRunAt
+		if _, err := Deliver(db, sender, schedule, Period(schedule.Frequency, to), to, now); err != nil {
+			log.Printf("report schedules: schedule %d: %v", schedule.ID, err)
+		}
+	}
+	return nil
+}
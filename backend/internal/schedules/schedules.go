@@ -0,0 +1,182 @@
+package schedules
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"systemacontrolya/internal/mailer"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/pdfdoc"
+	"systemacontrolya/internal/xlsxexport"
+
+	"gorm.io/gorm"
+)
+
+// Dir — каталог сформированных сводок.
+const Dir = "uploads/scheduled"
+
+var contentTypes = map[string]string{
+	"pdf":  "application/pdf",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Next возвращает ближайшую границу периода после after: полночь следующего дня,
+// понедельник следующей недели или первое число следующего месяца.
+func Next(frequency string, after time.Time) time.Time {
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, after.Location())
+	switch frequency {
+	case "weekly":
+		return day.AddDate(0, 0, 7-(int(day.Weekday())+6)%7)
+	case "monthly":
+		return time.Date(after.Year(), after.Month()+1, 1, 0, 0, 0, 0, after.Location())
+	}
+	return day.AddDate(0, 0, 1)
+}
+
+// Period возвращает начало периода, который заканчивается в to.
+func Period(frequency string, to time.Time) time.Time {
+	switch frequency {
+	case "weekly":
+		return to.AddDate(0, 0, -7)
+	case "monthly":
+		return to.AddDate(0, -1, 0)
+	}
+	return to.AddDate(0, 0, -1)
+}
+
+// Collect собирает сводку по проекту расписания или по всему портфелю за период [from, to).
+// Просрочка считается на момент now.
+func Collect(db *gorm.DB, schedule models.ReportSchedule, from, to, now time.Time) (models.PeriodSummary, error) {
+	summary := models.PeriodSummary{Title: "Портфель проектов", From: from, To: to, ByStatus: map[string]int64{}}
+	defects := func() *gorm.DB {
+		query := db.Model(&models.Defect{})
+		if schedule.ProjectID != nil {
+			query = query.Where("defects.project_id = ?", *schedule.ProjectID)
+		}
+		return query
+	}
+	if schedule.ProjectID != nil {
+		var project models.Project
+		if err := db.First(&project, *schedule.ProjectID).Error; err != nil {
+			return summary, err
+		}
+		summary.Title = "Проект: " + project.Name
+	}
+
+	var byStatus []struct {
+		Status string
+		Count  int64
+	}
+	if err := defects().Select("status, COUNT(*) AS count").Group("status").Scan(&byStatus).Error; err != nil {
+		return summary, err
+	}
+	for _, row := range byStatus {
+		summary.ByStatus[row.Status] = row.Count
+		summary.Total += row.Count
+	}
+	if err := defects().Where("created_at >= ? AND created_at < ?", from, to).Count(&summary.Created).Error; err != nil {
+		return summary, err
+	}
+	if err := defects().Where("closed_at >= ? AND closed_at < ?", from, to).Count(&summary.Closed).Error; err != nil {
+		return summary, err
+	}
+
+	details := func(query *gorm.DB) *gorm.DB {
+		return query.Preload("Project").Preload("Location").Preload("Assignee", models.WithDeleted)
+	}
+	if err := details(defects()).
+		Where("priority = ? AND created_at >= ? AND created_at < ?", "critical", from, to).
+		Order("created_at").
+		Find(&summary.NewCritical).Error; err != nil {
+		return summary, err
+	}
+	if err := details(defects()).
+		Where("status NOT IN ? AND due_date < ?", []string{"resolved", "closed"}, now).
+		Order("due_date").
+		Find(&summary.Overdue).Error; err != nil {
+		return summary, err
+	}
+
+	approved := db.Joins("JOIN reports ON reports.id = report_reviews.report_id AND reports.deleted_at IS NULL").
+		Where("report_reviews.stage = ? AND report_reviews.decision = ?", "manager", "approve").
+		Where("report_reviews.created_at >= ? AND report_reviews.created_at < ?", from, to)
+	if schedule.ProjectID != nil {
+		approved = approved.Where("reports.project_id = ?", *schedule.ProjectID)
+	}
+	if err := approved.
+		Preload("Report.Project").Preload("Report.Defect").Preload("Report.User", models.WithDeleted).
+		Order("report_reviews.created_at").
+		Find(&summary.Approved).Error; err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// Deliver формирует сводку за период [from, to), сохраняет файл, отправляет его получателям
+// и записывает результат в историю. Запись истории создаётся и при ошибке отправки.
+func Deliver(db *gorm.DB, sender mailer.Sender, schedule models.ReportSchedule, from, to, now time.Time) (models.ScheduledReport, error) {
+	history := models.ScheduledReport{ScheduleID: schedule.ID, PeriodFrom: from, PeriodTo: to, Status: "sent"}
+
+	filename, data, err := render(db, schedule, from, to, now)
+	if err == nil {
+		history.Filename = filename
+		err = sender.Send(mailer.Message{
+			To:      recipients(schedule),
+			Subject: fmt.Sprintf("%s сводка: %s", models.ScheduleFrequencyNames[schedule.Frequency], schedule.Name),
+			Body: fmt.Sprintf("Сводка «%s» за период %s — %s во вложении.",
+				schedule.Name, from.Format("02.01.2006 15:04"), to.Format("02.01.2006 15:04")),
+			Attachments: []mailer.Attachment{{Filename: filename, ContentType: contentTypes[schedule.Format], Data: data}},
+		})
+	}
+	if err != nil {
+		history.Status = "failed"
+		history.Error = err.Error()
+	}
+
+	if createErr := db.Create(&history).Error; createErr != nil {
+		return history, createErr
+	}
+	return history, err
+}
+
+func render(db *gorm.DB, schedule models.ReportSchedule, from, to, now time.Time) (string, []byte, error) {
+	summary, err := Collect(db, schedule, from, to, now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var buf bytes.Buffer
+	if schedule.Format == "xlsx" {
+		err = xlsxexport.WritePeriodSummary(summary, &buf)
+	} else {
+		err = pdfdoc.PeriodSummary(summary).Output(&buf)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := os.MkdirAll(Dir, 0o755); err != nil {
+		return "", nil, err
+	}
+	filename := fmt.Sprintf("summary_%d_%s.%s", schedule.ID, now.Format("20060102_150405"), schedule.Format)
+	if err := os.WriteFile(filepath.Join(Dir, filename), buf.Bytes(), 0o644); err != nil {
+		return "", nil, err
+	}
+	return filename, buf.Bytes(), nil
+}
+
+// recipients — владелец расписания и дополнительные адреса без повторов.
+func recipients(schedule models.ReportSchedule) []string {
+	seen := map[string]bool{}
+	var to []string
+	for _, email := range append([]string{schedule.User.Email}, schedule.Recipients...) {
+		if email != "" && !seen[email] {
+			seen[email] = true
+			to = append(to, email)
+		}
+	}
+	return to
+}
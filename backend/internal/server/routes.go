@@ -4,10 +4,25 @@ import (
 	"net/http"
 
 	"systemacontrolya/internal/handlers/admin"
+	"systemacontrolya/internal/handlers/analytics"
 	"systemacontrolya/internal/handlers/auth"
+	"systemacontrolya/internal/handlers/calendar"
+	"systemacontrolya/internal/handlers/customfields"
 	"systemacontrolya/internal/handlers/defects"
+	"systemacontrolya/internal/handlers/delegations"
+	"systemacontrolya/internal/handlers/imports"
+	"systemacontrolya/internal/handlers/labels"
+	"systemacontrolya/internal/handlers/links"
+	"systemacontrolya/internal/handlers/locations"
+	"systemacontrolya/internal/handlers/materials"
+	"systemacontrolya/internal/handlers/organizations"
+	"systemacontrolya/internal/handlers/plans"
 	"systemacontrolya/internal/handlers/projects"
+	"systemacontrolya/internal/handlers/qr"
 	"systemacontrolya/internal/handlers/reports"
+	"systemacontrolya/internal/handlers/schedules"
+	"systemacontrolya/internal/handlers/sla"
+	"systemacontrolya/internal/handlers/worklogs"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -20,6 +35,7 @@ func (s *Server) RegisterRoutes() http.Handler {
 		AllowOrigins:     []string{"http://localhost:3000"}, //Docker - 3001, Local - 3000
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
 		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type"},
+		ExposeHeaders:    []string{"Content-Disposition", "X-Stats-Refreshed-At"},
 		AllowCredentials: true,
 	}))
 
@@ -45,5 +61,65 @@ func (s *Server) RegisterRoutes() http.Handler {
 	reportHander := reports.NewReportsHandler(s.db.DB())
 	reportHander.RegisterRoutes(r)
 
+	//SLA
+	slaHandler := sla.NewSLAHandler(s.db.DB())
+	slaHandler.RegisterRoutes(r)
+
+	//Calendar
+	calendarHandler := calendar.NewCalendarHandler(s.db.DB())
+	calendarHandler.RegisterRoutes(r)
+
+	//Labels
+	labelsHandler := labels.NewLabelsHandler(s.db.DB())
+	labelsHandler.RegisterRoutes(r)
+
+	//Defect links
+	linksHandler := links.NewLinksHandler(s.db.DB())
+	linksHandler.RegisterRoutes(r)
+
+	//Custom fields
+	customFieldsHandler := customfields.NewCustomFieldsHandler(s.db.DB())
+	customFieldsHandler.RegisterRoutes(r)
+
+	//Locations
+	locationsHandler := locations.NewLocationsHandler(s.db.DB())
+	locationsHandler.RegisterRoutes(r)
+
+	//Floor plans
+	plansHandler := plans.NewPlansHandler(s.db.DB())
+	plansHandler.RegisterRoutes(r)
+
+	//QR labels
+	qrHandler := qr.NewQRHandler(s.db.DB())
+	qrHandler.RegisterRoutes(r)
+
+	//Defect imports
+	importsHandler := imports.NewImportsHandler(s.db.DB())
+	importsHandler.RegisterRoutes(r)
+
+	//Leader analytics
+	analyticsHandler := analytics.NewAnalyticsHandler(s.db.DB())
+	analyticsHandler.RegisterRoutes(r)
+
+	//Scheduled leader reports
+	schedulesHandler := schedules.NewSchedulesHandler(s.db.DB())
+	schedulesHandler.RegisterRoutes(r)
+
+	//Work logs
+	workLogsHandler := worklogs.NewWorkLogsHandler(s.db.DB())
+	workLogsHandler.RegisterRoutes(r)
+
+	//Materials and costs
+	materialsHandler := materials.NewMaterialsHandler(s.db.DB())
+	materialsHandler.RegisterRoutes(r)
+
+	//Contractor organizations
+	organizationsHandler := organizations.NewOrganizationsHandler(s.db.DB())
+	organizationsHandler.RegisterRoutes(r)
+
+	//Vacation delegation
+	delegationsHandler := delegations.NewDelegationsHandler(s.db.DB())
+	delegationsHandler.RegisterRoutes(r)
+
 	return r
 }
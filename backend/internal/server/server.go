@@ -7,6 +7,11 @@ import (
 	"strconv"
 
 	"systemacontrolya/internal/database"
+	"systemacontrolya/internal/mailer"
+	"systemacontrolya/internal/schedules"
+	"systemacontrolya/internal/similar"
+	"systemacontrolya/internal/stats"
+	"systemacontrolya/internal/trash"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -27,6 +32,11 @@ func NewServer() *http.Server {
 		db:   database.New(),
 	}
 
+	go trash.Run(NewServer.db.DB(), trash.RetentionFromEnv())
+	go stats.Run(NewServer.db.DB(), stats.IntervalFromEnv())
+	go schedules.Run(NewServer.db.DB(), mailer.FromEnv())
+	go similar.Backfill(NewServer.db.DB())
+
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", NewServer.port),
 		Handler: NewServer.RegisterRoutes(),
@@ -0,0 +1,89 @@
+package similar
+
+import (
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DHash считает разностный хеш изображения: картинка уменьшается до 9×8 в оттенках серого,
+// каждый бит — сравнение яркости соседних по горизонтали ячеек. Хеш устойчив к масштабу и сжатию.
+func DHash(r io.Reader) (uint64, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return 0, err
+	}
+
+	const width, height = 9, 8
+	var cells [height][width]float64
+	bounds := img.Bounds()
+	for row := range height {
+		y0 := bounds.Min.Y + row*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(row+1)*bounds.Dy()/height, y0+1)
+		for col := range width {
+			x0 := bounds.Min.X + col*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(col+1)*bounds.Dx()/width, x0+1)
+			cells[row][col] = brightness(img, x0, y0, x1, y1)
+		}
+	}
+
+	var hash uint64
+	for row := range height {
+		for col := range width - 1 {
+			hash <<= 1
+			if cells[row][col] > cells[row][col+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// brightness — средняя яркость прямоугольника; большие области прореживаются до ~32×32 точек.
+func brightness(img image.Image, x0, y0, x1, y1 int) float64 {
+	stepX := max((x1-x0)/32, 1)
+	stepY := max((y1-y0)/32, 1)
+	var sum float64
+	var count int
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			count++
+		}
+	}
+	return sum / float64(count)
+}
+
+// Distance — число различающихся битов двух хешей, от 0 (одинаковые) до 64.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// HashFile считает хеш файла вложения по пути вида /uploads/...; не изображения пропускаются.
+func HashFile(path string) (uint64, bool) {
+	if !isImage(path) {
+		return 0, false
+	}
+	file, err := os.Open("." + path)
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	hash, err := DHash(file)
+	return hash, err == nil
+}
+
+func isImage(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
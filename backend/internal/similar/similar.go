@@ -0,0 +1,193 @@
+package similar
+
+import (
+	"cmp"
+	"log"
+	"math"
+	"mime/multipart"
+	"slices"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxDistance — наибольшее расстояние между хешами, при котором фото считаются похожими.
+	maxDistance = 10
+	// limit — сколько похожих дефектов возвращается.
+	limit = 10
+)
+
+// Query описывает новый дефект: похожие ищутся среди незакрытых дефектов того же проекта,
+// а если место указано — того же места.
+type Query struct {
+	ProjectID   uint
+	LocationID  *uint
+	Title       string
+	Description string
+	Hashes      []uint64
+	ExcludeID   uint
+}
+
+// Match — похожий дефект. Score объединяет текстовую и фото-оценку: 1 - (1-text)(1-photo),
+// так что совпадение по обоим признакам ранжируется выше совпадения по одному.
+type Match struct {
+	Defect        models.Defect `json:"defect"`
+	Score         float64       `json:"score"`
+	TextScore     float64       `json:"text_score"`
+	PhotoScore    float64       `json:"photo_score"`
+	PhotoDistance *int          `json:"photo_distance"`
+}
+
+// Find возвращает похожие дефекты по убыванию оценки. Текст сравнивается триграммами pg_trgm:
+// название весит 0.7, описание 0.3. Фото сравниваются по dHash вложений.
+func Find(db *gorm.DB, q Query) ([]Match, error) {
+	scope := func(query *gorm.DB) *gorm.DB {
+		query = query.Where("d.deleted_at IS NULL AND d.project_id = ? AND d.status <> ?", q.ProjectID, "closed")
+		if q.LocationID != nil {
+			query = query.Where("d.location_id = ?", *q.LocationID)
+		}
+		if q.ExcludeID != 0 {
+			query = query.Where("d.id <> ?", q.ExcludeID)
+		}
+		return query
+	}
+	matches := map[uint]*Match{}
+	get := func(id uint) *Match {
+		if matches[id] == nil {
+			matches[id] = &Match{}
+		}
+		return matches[id]
+	}
+
+	if q.Title != "" || q.Description != "" {
+		var rows []struct {
+			ID               uint
+			TitleScore       float64
+			DescriptionScore float64
+		}
+		if err := scope(db.Table("defects d")).
+			Select("d.id, similarity(d.title, ?) AS title_score, similarity(d.description, ?) AS description_score", q.Title, q.Description).
+			Where("d.title % ? OR d.description % ?", q.Title, q.Description).
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			score := row.TitleScore
+			if q.Description != "" {
+				score = 0.7*row.TitleScore + 0.3*row.DescriptionScore
+			}
+			get(row.ID).TextScore = round(score)
+		}
+	}
+
+	if len(q.Hashes) > 0 {
+		var rows []struct {
+			DefectID uint
+			Hash     int64
+		}
+		if err := scope(db.Table("defect_photo_hashes h").Joins("JOIN defects d ON d.id = h.defect_id")).
+			Select("h.defect_id, h.hash").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		best := map[uint]int{}
+		for _, row := range rows {
+			for _, hash := range q.Hashes {
+				distance := Distance(uint64(row.Hash), hash)
+				if previous, ok := best[row.DefectID]; distance <= maxDistance && (!ok || distance < previous) {
+					best[row.DefectID] = distance
+				}
+			}
+		}
+		for id, distance := range best {
+			m := get(id)
+			m.PhotoDistance = &distance
+			m.PhotoScore = round(1 - float64(distance)/float64(maxDistance+1))
+		}
+	}
+
+	if len(matches) == 0 {
+		return []Match{}, nil
+	}
+	ids := make([]uint, 0, len(matches))
+	for id := range matches {
+		ids = append(ids, id)
+	}
+	var defects []models.Defect
+	if err := db.Preload("Location").Preload("Assignee", models.WithDeleted).Where("id IN ?", ids).Find(&defects).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]Match, 0, len(defects))
+	for _, defect := range defects {
+		m := matches[defect.ID]
+		m.Defect = defect
+		m.Score = round(1 - (1-m.TextScore)*(1-m.PhotoScore))
+		result = append(result, *m)
+	}
+	slices.SortFunc(result, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(b.Defect.ID, a.Defect.ID))
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// HashUploads считает хеши загружаемых изображений, не сохраняя их; остальные файлы пропускаются.
+func HashUploads(files []*multipart.FileHeader) []uint64 {
+	var hashes []uint64
+	for _, header := range files {
+		if !isImage(header.Filename) {
+			continue
+		}
+		file, err := header.Open()
+		if err != nil {
+			continue
+		}
+		hash, err := DHash(file)
+		file.Close()
+		if err == nil {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// StoreHashes сохраняет хеши изображений из вложений дефекта; уже посчитанные пути пропускаются.
+func StoreHashes(db *gorm.DB, defectID uint, paths []string) error {
+	var rows []models.DefectPhotoHash
+	for _, path := range paths {
+		if hash, ok := HashFile(path); ok {
+			rows = append(rows, models.DefectPhotoHash{DefectID: defectID, Path: path, Hash: int64(hash)})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// Backfill считает хеши вложений дефектов, заведённых до появления поиска похожих.
+func Backfill(db *gorm.DB) {
+	var defects []models.Defect
+	if err := db.Select("id", "attachments").
+		Where("jsonb_typeof(attachments) = 'array' AND attachments <> '[]'::jsonb").
+		Where("NOT EXISTS (SELECT 1 FROM defect_photo_hashes h WHERE h.defect_id = defects.id)").
+		Find(&defects).Error; err != nil {
+		log.Printf("photo hashes backfill: %v", err)
+		return
+	}
+	for _, defect := range defects {
+		if err := StoreHashes(db, defect.ID, defect.Attachments); err != nil {
+			log.Printf("photo hashes backfill: defect %d: %v", defect.ID, err)
+		}
+	}
+}
+
+func round(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
@@ -0,0 +1,99 @@
+package sla
+
+import (
+	"time"
+
+	"systemacontrolya/internal/calendar"
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultPolicies используются, если для проекта не настроена политика по приоритету.
+var DefaultPolicies = map[string]models.SLAPolicy{
+	"critical": {Priority: "critical", ResponseHours: 4, ResolutionHours: 24, ExtensionHours: 24},
+	"high":     {Priority: "high", ResponseHours: 8, ResolutionHours: 72, ExtensionHours: 48},
+	"medium":   {Priority: "medium", ResponseHours: 24, ResolutionHours: 168, ExtensionHours: 72},
+	"low":      {Priority: "low", ResponseHours: 48, ResolutionHours: 336, ExtensionHours: 72},
+}
+
+func PolicyFor(db *gorm.DB, projectID uint, priority string) models.SLAPolicy {
+	var policy models.SLAPolicy
+	if err := db.Where("project_id = ? AND priority = ?", projectID, priority).First(&policy).Error; err == nil {
+		return policy
+	}
+
+	policy, ok := DefaultPolicies[priority]
+	if !ok {
+		policy = DefaultPolicies["medium"]
+	}
+	policy.ProjectID = projectID
+	return policy
+}
+
+// ProjectPolicies возвращает политики проекта по всем приоритетам с подстановкой значений по умолчанию.
+func ProjectPolicies(db *gorm.DB, projectID uint) ([]models.SLAPolicy, error) {
+	var stored []models.SLAPolicy
+	if err := db.Where("project_id = ?", projectID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	byPriority := make(map[string]models.SLAPolicy, len(stored))
+	for _, p := range stored {
+		byPriority[p.Priority] = p
+	}
+
+	policies := make([]models.SLAPolicy, 0, len(DefaultPolicies))
+	for _, priority := range []string{"critical", "high", "medium", "low"} {
+		p, ok := byPriority[priority]
+		if !ok {
+			p = DefaultPolicies[priority]
+			p.ProjectID = projectID
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// ApplyOnCreate выставляет срок реакции для нового дефекта. Сроки считаются в рабочих часах календаря проекта.
+func ApplyOnCreate(db *gorm.DB, defect *models.Defect, now time.Time) {
+	policy := PolicyFor(db, defect.ProjectID, defect.Priority)
+	responseDue := calendar.ForProject(db, defect.ProjectID).AddWorkingHours(now, policy.ResponseHours)
+	defect.ResponseDueDate = &responseDue
+}
+
+// ApplyOnAssign фиксирует момент назначения и, если срок не задан вручную, выставляет срок устранения.
+func ApplyOnAssign(db *gorm.DB, defect *models.Defect, now time.Time) {
+	if defect.AssignedAt == nil {
+		defect.AssignedAt = &now
+	}
+	if defect.DueDate == nil {
+		policy := PolicyFor(db, defect.ProjectID, defect.Priority)
+		due := calendar.ForProject(db, defect.ProjectID).AddWorkingHours(now, policy.ResolutionHours)
+		defect.DueDate = &due
+	}
+}
+
+// ExtensionDue возвращает новый срок при возврате дефекта на доработку.
+func ExtensionDue(db *gorm.DB, defect *models.Defect, now time.Time) time.Time {
+	policy := PolicyFor(db, defect.ProjectID, defect.Priority)
+	return calendar.ForProject(db, defect.ProjectID).AddWorkingHours(now, policy.ExtensionHours)
+}
+
+// StampStatus ведёт отметки устранения и закрытия после смены статуса с previous: каждое устранение
+// и закрытие ставит новую отметку, а возврат дефекта в работу снимает отметки пройденных этапов.
+func StampStatus(defect *models.Defect, previous string, now time.Time) {
+	if defect.Status == previous {
+		return
+	}
+	switch defect.Status {
+	case "closed":
+		defect.ClosedAt = &now
+	case "resolved":
+		defect.ResolvedAt = &now
+		defect.ClosedAt = nil
+	default:
+		defect.ResolvedAt = nil
+		defect.ClosedAt = nil
+	}
+}
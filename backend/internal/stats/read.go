@@ -0,0 +1,126 @@
+package stats
+
+import (
+	"time"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// FreshnessHeader — заголовок ответа со временем последнего обновления статистики (RFC 3339).
+const FreshnessHeader = "X-Stats-Refreshed-At"
+
+// FormatFreshness готовит время обновления для FreshnessHeader; пустая строка — статистика ещё не считалась.
+func FormatFreshness(refreshedAt *time.Time) string {
+	if refreshedAt == nil {
+		return ""
+	}
+	return refreshedAt.Format(time.RFC3339)
+}
+
+// DailyStatus — снимок одного дня: число дефектов по статусам и просроченных.
+type DailyStatus struct {
+	Day      time.Time        `json:"day"`
+	ByStatus map[string]int64 `json:"by_status"`
+	Overdue  int64            `json:"overdue"`
+}
+
+// DefectCounts возвращает число дефектов по статусам из последнего снимка.
+func DefectCounts(db *gorm.DB) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Total  int64
+	}
+	if err := db.Table("defect_stats_daily").
+		Select("status, SUM(total) AS total").
+		Where("day = (SELECT MAX(day) FROM defect_stats_daily)").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[row.Status] = row.Total
+	}
+	return counts, nil
+}
+
+// LabelCounts возвращает дефекты по меткам; метки без пересчитанной статистики показываются с нулями.
+func LabelCounts(db *gorm.DB) ([]models.LabelStats, error) {
+	var rows []models.LabelStats
+	err := db.Table("labels").
+		Select(`labels.id, labels.name, labels.color, labels.kind, labels.project_id,
+			COALESCE(s.total, 0) AS total, COALESCE(s.open, 0) AS open, COALESCE(s.closed, 0) AS closed`).
+		Joins("JOIN projects p ON p.id = labels.project_id").
+		Joins("LEFT JOIN defect_label_stats s ON s.label_id = labels.id").
+		Where("p.deleted_at IS NULL").
+		Order("total DESC, labels.name").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ReportDay — число отчётов, поступивших за день.
+type ReportDay struct {
+	Date  time.Time `json:"date"`
+	Count int       `json:"count"`
+}
+
+// DailyReports возвращает число поступивших отчётов по дням за последние days дней.
+func DailyReports(db *gorm.DB, days int) ([]ReportDay, error) {
+	var rows []ReportDay
+	err := db.Table("report_stats_daily").
+		Select("day AS date, SUM(total) AS count").
+		Where("day >= CURRENT_DATE - ?::int", days).
+		Group("day").
+		Order("day").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ProjectSummaries возвращает сводку по проектам из подзапроса ID.
+func ProjectSummaries(db *gorm.DB, projectIDs *gorm.DB) ([]models.ProjectSummary, error) {
+	var rows []models.ProjectSummary
+	err := db.Table("projects p").
+		Select(`p.id, p.name, p.description,
+			COALESCE(s.defects, 0) AS defects_count,
+			COALESCE(s.authors, 0) AS engineers_count,
+			COALESCE(s.assignees, 0) AS assignees_count`).
+		Joins("LEFT JOIN project_stats s ON s.project_id = p.id").
+		Where("p.deleted_at IS NULL AND p.id IN (?)", projectIDs).
+		Order("p.id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// Daily возвращает снимки по дням за период [from, to) для проекта или всех проектов.
+func Daily(db *gorm.DB, projectID *uint, from, to time.Time) ([]DailyStatus, error) {
+	query := db.Table("defect_stats_daily").
+		Select("day, status, SUM(total) AS total, SUM(overdue) AS overdue").
+		Where("day >= ? AND day < ?", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if projectID != nil {
+		query = query.Where("project_id = ?", *projectID)
+	}
+
+	var rows []struct {
+		Day     time.Time
+		Status  string
+		Total   int64
+		Overdue int64
+	}
+	if err := query.Group("day, status").Order("day").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	days := []DailyStatus{}
+	for _, row := range rows {
+		if len(days) == 0 || !days[len(days)-1].Day.Equal(row.Day) {
+			days = append(days, DailyStatus{Day: row.Day, ByStatus: map[string]int64{}})
+		}
+		current := &days[len(days)-1]
+		current.ByStatus[row.Status] = row.Total
+		current.Overdue += row.Overdue
+	}
+	return days, nil
+}
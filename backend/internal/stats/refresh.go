@@ -0,0 +1,159 @@
+package stats
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultInterval — период обновления хранилища, если STATS_REFRESH_MINUTES не задан.
+const DefaultInterval = 5 * time.Minute
+
+const refreshName = "dashboard"
+
+// refreshLock — ключ advisory-блокировки, чтобы фоновое и ручное обновление не шли одновременно.
+const refreshLock = 43_001
+
+// IntervalFromEnv читает период обновления статистики в минутах из STATS_REFRESH_MINUTES.
+func IntervalFromEnv() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("STATS_REFRESH_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return DefaultInterval
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// Run обновляет хранилище статистики с заданным периодом.
+func Run(db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := Refresh(db, time.Now()); err != nil {
+			log.Printf("stats refresh: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// Touch помечает проекты для пересчёта при следующем обновлении. Нужен для изменений,
+// которые не меняют updated_at дефектов: метки, окончательное удаление, восстановление из корзины.
+func Touch(db *gorm.DB, projectIDs ...uint) error {
+	for _, projectID := range projectIDs {
+		if err := db.Exec("INSERT INTO stats_pending (project_id) VALUES (?) ON CONFLICT DO NOTHING", projectID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Refresh пересчитывает статистику проектов, в которых что-то изменилось с прошлого обновления:
+// изменённые дефекты, новые отчёты, наступившие сроки и помеченные через Touch. Первое обновление
+// за день пересчитывает все проекты — так у каждого появляется снимок на новый день.
+func Refresh(db *gorm.DB, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", refreshLock).Error; err != nil {
+			return err
+		}
+
+		last, err := Freshness(tx)
+		if err != nil {
+			return err
+		}
+
+		var projectIDs []uint
+		if last == nil || day(last.In(now.Location())) != day(now) {
+			err = tx.Model(&models.Project{}).Pluck("id", &projectIDs).Error
+		} else {
+			err = tx.Raw(`
+				SELECT project_id FROM defects WHERE updated_at >= @last
+				UNION SELECT project_id FROM defects WHERE due_date >= @last AND due_date < @now AND deleted_at IS NULL
+				UNION SELECT project_id FROM reports WHERE created_at >= @last
+				UNION SELECT project_id FROM stats_pending
+			`, map[string]any{"last": *last, "now": now}).Scan(&projectIDs).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(projectIDs) > 0 {
+			if err := refreshProjects(tx, projectIDs, now); err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM stats_pending WHERE project_id IN ?", projectIDs).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(`INSERT INTO stats_refreshes (name, refreshed_at) VALUES (?, ?)
+			ON CONFLICT (name) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at`, refreshName, now).Error
+	})
+}
+
+// Freshness возвращает время последнего обновления хранилища или nil, если его ещё не было.
+func Freshness(db *gorm.DB) (*time.Time, error) {
+	var refreshedAt []time.Time
+	if err := db.Table("stats_refreshes").Where("name = ?", refreshName).Pluck("refreshed_at", &refreshedAt).Error; err != nil {
+		return nil, err
+	}
+	if len(refreshedAt) == 0 {
+		return nil, nil
+	}
+	return &refreshedAt[0], nil
+}
+
+func refreshProjects(tx *gorm.DB, projectIDs []uint, now time.Time) error {
+	today := day(now)
+
+	statements := []struct {
+		sql  string
+		args []any
+	}{
+		{"DELETE FROM defect_stats_daily WHERE day = ? AND project_id IN ?", []any{today, projectIDs}},
+		{`INSERT INTO defect_stats_daily (day, project_id, status, priority, total, overdue)
+			SELECT ?, project_id, status, priority, COUNT(*),
+				COUNT(*) FILTER (WHERE due_date < ? AND status NOT IN ('resolved', 'closed'))
+			FROM defects
+			WHERE deleted_at IS NULL AND project_id IN ?
+			GROUP BY project_id, status, priority`, []any{today, now, projectIDs}},
+
+		{"DELETE FROM defect_label_stats WHERE project_id IN ?", []any{projectIDs}},
+		{`INSERT INTO defect_label_stats (label_id, project_id, total, open, closed)
+			SELECT l.id, l.project_id, COUNT(d.id),
+				COUNT(d.id) FILTER (WHERE d.status <> 'closed'),
+				COUNT(d.id) FILTER (WHERE d.status = 'closed')
+			FROM labels l
+			LEFT JOIN defect_labels dl ON dl.label_id = l.id
+			LEFT JOIN defects d ON d.id = dl.defect_id AND d.deleted_at IS NULL
+			WHERE l.project_id IN ?
+			GROUP BY l.id, l.project_id`, []any{projectIDs}},
+
+		{"DELETE FROM project_stats WHERE project_id IN ?", []any{projectIDs}},
+		{`INSERT INTO project_stats (project_id, defects, authors, assignees)
+			SELECT p.id, COUNT(d.id), COUNT(DISTINCT d.author_id), COUNT(DISTINCT d.assignee_id)
+			FROM projects p
+			LEFT JOIN defects d ON d.project_id = p.id AND d.deleted_at IS NULL
+			WHERE p.id IN ? AND p.deleted_at IS NULL
+			GROUP BY p.id`, []any{projectIDs}},
+
+		{"DELETE FROM report_stats_daily WHERE project_id IN ?", []any{projectIDs}},
+		{`INSERT INTO report_stats_daily (day, project_id, total)
+			SELECT DATE(created_at), project_id, COUNT(*)
+			FROM reports
+			WHERE deleted_at IS NULL AND project_id IN ?
+			GROUP BY DATE(created_at), project_id`, []any{projectIDs}},
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement.sql, statement.args...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func day(t time.Time) string {
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,101 @@
+package trash
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"systemacontrolya/internal/lifecycle"
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultRetention — сколько запись хранится в корзине, если TRASH_RETENTION_DAYS не задан.
+const DefaultRetention = 30 * 24 * time.Hour
+
+const purgeInterval = time.Hour
+
+// RetentionFromEnv читает срок хранения корзины в днях из TRASH_RETENTION_DAYS.
+func RetentionFromEnv() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		return DefaultRetention
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Run раз в час окончательно удаляет записи, пролежавшие в корзине дольше retention.
+func Run(db *gorm.DB, retention time.Duration) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		if err := Purge(db, time.Now().Add(-retention)); err != nil {
+			log.Printf("trash purge: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// Purge окончательно удаляет записи, перемещённые в корзину раньше before. Проекты удаляются первыми:
+// перед удалением данные проекта вместе с его удалёнными дефектами выгружаются в архив.
+// Пользователь удаляется, только если на него не ссылаются дефекты и отчёты.
+func Purge(db *gorm.DB, before time.Time) error {
+	unscoped := db.Unscoped().Session(&gorm.Session{})
+
+	var projects []models.Project
+	if err := unscoped.Where("deleted_at < ?", before).Find(&projects).Error; err != nil {
+		return err
+	}
+	for _, project := range projects {
+		if err := purgeProject(unscoped, project); err != nil {
+			log.Printf("trash purge: project %d: %v", project.ID, err)
+		}
+	}
+
+	if err := unscoped.Where("deleted_at < ?", before).Delete(&models.Report{}).Error; err != nil {
+		return err
+	}
+	if err := unscoped.Where("deleted_at < ? AND NOT EXISTS (SELECT 1 FROM reports WHERE reports.defect_id = defects.id)", before).
+		Delete(&models.Defect{}).Error; err != nil {
+		return err
+	}
+
+	var users []models.User
+	if err := unscoped.Where("deleted_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM defects WHERE defects.author_id = users.id OR defects.assignee_id = users.id)").
+		Where("NOT EXISTS (SELECT 1 FROM reports WHERE reports.user_id = users.id)").
+		Find(&users).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		// Остальные ссылки (журнал, рецензии, проекты) могут не дать удалить пользователя — тогда он остаётся в корзине.
+		if err := unscoped.Delete(&user).Error; err != nil {
+			log.Printf("trash purge: user %d: %v", user.ID, err)
+		}
+	}
+	return nil
+}
+
+func purgeProject(db *gorm.DB, project models.Project) error {
+	filename, err := lifecycle.Export(db, project, time.Now())
+	if err != nil {
+		return err
+	}
+	log.Printf("trash purge: project %d exported to %s", project.ID, filename)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", project.ID).Delete(&models.Report{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ?", project.ID).Delete(&models.Defect{}).Error; err != nil {
+			return err
+		}
+		// Иерархия мест защищена от удаления родителя раньше детей, поэтому связи снимаются заранее.
+		if err := tx.Model(&models.Location{}).Where("project_id = ?", project.ID).Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&project).Error
+	})
+}
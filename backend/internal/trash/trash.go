@@ -0,0 +1,179 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/stats"
+
+	"gorm.io/gorm"
+)
+
+// Kinds — таблицы, записи которых удаляются в корзину.
+var Kinds = []string{"defects", "reports", "users", "projects"}
+
+var ErrNotFound = errors.New("запись в корзине не найдена")
+
+// ConflictError — причина, по которой запись нельзя восстановить. Остальные ошибки Restore — ошибки базы.
+type ConflictError struct {
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Reason
+}
+
+// Item — запись корзины. PurgeAt — момент, после которого запись будет удалена окончательно.
+type Item struct {
+	Kind      string    `json:"kind"`
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	ProjectID *uint     `json:"project_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// titles — как показывать запись каждого вида в корзине.
+var titles = map[string]string{
+	"defects":  "title AS title, project_id AS project_id",
+	"reports":  "title AS title, project_id AS project_id",
+	"users":    "CONCAT(last_name, ' ', first_name, ' <', email, '>') AS title, NULL AS project_id",
+	"projects": "name AS title, id AS project_id",
+}
+
+// DeleteDefect перемещает дефект в корзину вместе с его отчётами. Отчёты получают ту же отметку
+// удаления, по ней они восстанавливаются вместе с дефектом.
+func DeleteDefect(db *gorm.DB, defect models.Defect, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Report{}).Where("defect_id = ?", defect.ID).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&defect).Update("deleted_at", now).Error
+	})
+}
+
+// DeleteProject перемещает в корзину проект, его дефекты и отчёты.
+func DeleteProject(db *gorm.DB, project models.Project, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Report{}).Where("project_id = ?", project.ID).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Defect{}).Where("project_id = ?", project.ID).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&project).Update("deleted_at", now).Error
+	})
+}
+
+// List возвращает записи корзины, новые сверху. Пустой kind — все виды.
+func List(db *gorm.DB, kind string, retention time.Duration) ([]Item, error) {
+	kinds := Kinds
+	if kind != "" {
+		kinds = []string{kind}
+	}
+
+	items := []Item{}
+	for _, kind := range kinds {
+		var rows []Item
+		if err := db.Table(kind).
+			Select("id, " + titles[kind] + ", deleted_at").
+			Where("deleted_at IS NOT NULL").
+			Order("deleted_at DESC").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			row.Kind = kind
+			row.PurgeAt = row.DeletedAt.Add(retention)
+			items = append(items, row)
+		}
+	}
+	return items, nil
+}
+
+// Restore возвращает запись из корзины. Дефект или отчёт восстанавливается, только если его проект
+// и дефект не удалены; вместе с проектом и дефектом возвращаются записи, удалённые с ними.
+func Restore(db *gorm.DB, kind string, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		switch kind {
+		case "defects":
+			var defect models.Defect
+			if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&defect, id).Error; err != nil {
+				return ErrNotFound
+			}
+			if removed, err := deleted(tx, &models.Project{}, defect.ProjectID); err != nil || removed {
+				return conflict(err, "проект дефекта удалён, сначала восстановите проект")
+			}
+			if err := restoreWith(tx, &models.Report{}, "defect_id", defect.ID, defect.DeletedAt.Time); err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&defect).Update("deleted_at", nil).Error
+		case "reports":
+			var report models.Report
+			if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&report, id).Error; err != nil {
+				return ErrNotFound
+			}
+			if removed, err := deleted(tx, &models.Defect{}, report.DefectID); err != nil || removed {
+				return conflict(err, "дефект отчёта удалён, сначала восстановите дефект")
+			}
+			if err := tx.Unscoped().Model(&report).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			return stats.Touch(tx, report.ProjectID)
+		case "users":
+			var user models.User
+			if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+				return ErrNotFound
+			}
+			if busy, err := taken(tx, &models.User{}, "email", user.Email); err != nil || busy {
+				return conflict(err, "email %s уже занят другим пользователем", user.Email)
+			}
+			return tx.Unscoped().Model(&user).Update("deleted_at", nil).Error
+		case "projects":
+			var project models.Project
+			if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&project, id).Error; err != nil {
+				return ErrNotFound
+			}
+			if busy, err := taken(tx, &models.Project{}, "name", project.Name); err != nil || busy {
+				return conflict(err, "название «%s» уже занято другим проектом", project.Name)
+			}
+			if err := restoreWith(tx, &models.Defect{}, "project_id", project.ID, project.DeletedAt.Time); err != nil {
+				return err
+			}
+			if err := restoreWith(tx, &models.Report{}, "project_id", project.ID, project.DeletedAt.Time); err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&project).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			return stats.Touch(tx, project.ID)
+		}
+		return ErrNotFound
+	})
+}
+
+func restoreWith(tx *gorm.DB, model any, column string, id uint, deletedAt time.Time) error {
+	return tx.Unscoped().Model(model).Where(column+" = ? AND deleted_at = ?", id, deletedAt).Update("deleted_at", nil).Error
+}
+
+func deleted(tx *gorm.DB, model any, id uint) (bool, error) {
+	var count int64
+	err := tx.Unscoped().Model(model).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&count).Error
+	return count > 0, err
+}
+
+func taken(tx *gorm.DB, model any, column, value string) (bool, error) {
+	var count int64
+	err := tx.Model(model).Where(column+" = ?", value).Count(&count).Error
+	return count > 0, err
+}
+
+// conflict возвращает ошибку проверки как есть, а иначе — причину отказа.
+func conflict(err error, format string, args ...any) error {
+	if err != nil {
+		return err
+	}
+	return &ConflictError{Reason: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,177 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+
+	"systemacontrolya/internal/imports"
+	"systemacontrolya/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var columnAliases = map[string][]string{
+	"email":       {"email", "e-mail", "почта"},
+	"last_name":   {"last_name", "фамилия"},
+	"first_name":  {"first_name", "имя"},
+	"middle_name": {"middle_name", "отчество"},
+	"role":        {"role", "role_id", "роль"},
+}
+
+// roleAliases — английские названия ролей, которые встречаются в выгрузках из других систем.
+var roleAliases = map[string]string{
+	"admin":    "Админ",
+	"engineer": "Инженер",
+	"manager":  "Менеджер",
+	"leader":   "Руководитель",
+	"assignee": assigneeRole,
+}
+
+type ImportRow struct {
+	Line       int      `json:"line"`
+	Email      string   `json:"email"`
+	LastName   string   `json:"last_name"`
+	FirstName  string   `json:"first_name"`
+	MiddleName string   `json:"middle_name"`
+	RoleID     uint     `json:"role_id,omitempty"`
+	Role       string   `json:"role,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// RoleMapper сопоставляет значение колонки «Роль» с ролью системы: по ID, названию,
+// английскому псевдониму или по пользовательскому сопоставлению вида {"ГИП": "Менеджер"}.
+type RoleMapper struct {
+	roles  []models.Role
+	custom map[string]string
+}
+
+func NewRoleMapper(db *gorm.DB, raw string) (*RoleMapper, error) {
+	mapper := &RoleMapper{custom: map[string]string{}}
+	if err := db.Find(&mapper.roles).Error; err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(raw) == "" {
+		return mapper, nil
+	}
+
+	var custom map[string]string
+	if err := json.Unmarshal([]byte(raw), &custom); err != nil {
+		return nil, fmt.Errorf("сопоставление ролей должно быть объектом JSON")
+	}
+	for value, role := range custom {
+		if _, ok := mapper.byName(role); !ok {
+			return nil, fmt.Errorf("роль «%s» не существует", role)
+		}
+		mapper.custom[strings.ToLower(strings.TrimSpace(value))] = role
+	}
+	return mapper, nil
+}
+
+func (m *RoleMapper) Resolve(value string) (models.Role, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if name, ok := m.custom[value]; ok {
+		return m.byName(name)
+	}
+	if name, ok := roleAliases[value]; ok {
+		return m.byName(name)
+	}
+	if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+		index := slices.IndexFunc(m.roles, func(r models.Role) bool { return r.ID == uint(id) })
+		if index >= 0 {
+			return m.roles[index], true
+		}
+	}
+	return m.byName(value)
+}
+
+func (m *RoleMapper) byName(name string) (models.Role, bool) {
+	index := slices.IndexFunc(m.roles, func(r models.Role) bool { return strings.EqualFold(r.Name, name) })
+	if index < 0 {
+		return models.Role{}, false
+	}
+	return m.roles[index], true
+}
+
+// CheckImport разбирает строки файла пользователей и проверяет их: обязательные поля,
+// формат и уникальность email (в файле и в базе), роль.
+func CheckImport(db *gorm.DB, table *imports.Table, roles *RoleMapper) ([]ImportRow, error) {
+	columns := map[string]int{}
+	for column, title := range table.Header {
+		for field, names := range columnAliases {
+			if _, taken := columns[field]; !taken && slices.Contains(names, strings.ToLower(title)) {
+				columns[field] = column
+			}
+		}
+	}
+	for _, field := range []string{"email", "last_name", "first_name", "role"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("в файле нет колонки %q", field)
+		}
+	}
+	cell := func(row imports.Row, field string) string {
+		column, ok := columns[field]
+		if !ok {
+			return ""
+		}
+		return row.Cell(column)
+	}
+
+	var existing []string
+	if err := db.Model(&models.User{}).Pluck("LOWER(email)", &existing).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]int{}
+	for _, email := range existing {
+		seen[email] = 0
+	}
+
+	rows := make([]ImportRow, 0, len(table.Rows))
+	for _, source := range table.Rows {
+		row := ImportRow{
+			Line:       source.Line,
+			Email:      cell(source, "email"),
+			LastName:   cell(source, "last_name"),
+			FirstName:  cell(source, "first_name"),
+			MiddleName: cell(source, "middle_name"),
+		}
+		fail := func(format string, args ...any) {
+			row.Errors = append(row.Errors, fmt.Sprintf(format, args...))
+		}
+
+		email := strings.ToLower(row.Email)
+		if address, err := mail.ParseAddress(row.Email); err != nil || address.Address != row.Email || len(row.Email) > 50 {
+			fail("Неверный email «%s»", row.Email)
+		} else if line, ok := seen[email]; ok {
+			if line == 0 {
+				fail("Пользователь с email %s уже существует", row.Email)
+			} else {
+				fail("Email %s повторяется в строке %d", row.Email, line)
+			}
+		} else {
+			seen[email] = row.Line
+		}
+
+		if row.LastName == "" || row.FirstName == "" {
+			fail("Фамилия и имя обязательны")
+		}
+		for _, name := range []string{row.LastName, row.FirstName, row.MiddleName} {
+			if len([]rune(name)) > 30 {
+				fail("«%s» длиннее 30 символов", name)
+			}
+		}
+
+		if role, ok := roles.Resolve(cell(source, "role")); ok {
+			row.RoleID = role.ID
+			row.Role = role.Name
+		} else {
+			fail("Неизвестная роль «%s»", cell(source, "role"))
+		}
+
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
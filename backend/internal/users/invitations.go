@@ -0,0 +1,85 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"systemacontrolya/internal/mailer"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// InvitationTTL — срок действия ссылки-приглашения.
+const InvitationTTL = 7 * 24 * time.Hour
+
+// NewInvitation создаёт одноразовое приглашение. В базе хранится только хеш токена,
+// сам токен возвращается один раз для ссылки.
+func NewInvitation(db *gorm.DB, userID, createdByID uint, now time.Time) (models.Invitation, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return models.Invitation{}, "", err
+	}
+	token := hex.EncodeToString(secret)
+
+	invitation := models.Invitation{
+		TokenHash:   HashToken(token),
+		ExpiresAt:   now.Add(InvitationTTL),
+		UserID:      userID,
+		CreatedByID: createdByID,
+	}
+	if err := db.Create(&invitation).Error; err != nil {
+		return models.Invitation{}, "", err
+	}
+	return invitation, token, nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func InvitationLink(token string) string {
+	return utils.AppURL() + "/invite/" + token
+}
+
+// SendInvitation отправляет ссылку-приглашение на почту пользователя и отмечает время отправки.
+func SendInvitation(db *gorm.DB, sender mailer.Sender, invitation *models.Invitation, user models.User, token string) error {
+	body := fmt.Sprintf(
+		"Здравствуйте, %s!\n\n"+
+			"Для вас создана учётная запись в системе контроля дефектов.\n"+
+			"Чтобы задать пароль и войти, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка одноразовая и действует до %s.\n",
+		user.FirstName, InvitationLink(token), invitation.ExpiresAt.Format("02.01.2006 15:04"))
+
+	if err := sender.Send(mailer.Message{To: []string{user.Email}, Subject: "Приглашение в систему контроля дефектов", Body: body}); err != nil {
+		return err
+	}
+	now := time.Now()
+	invitation.SentAt = &now
+	return db.Model(invitation).Update("sent_at", now).Error
+}
+
+// RevokePending отзывает все неиспользованные приглашения пользователя, чтобы действовала только последняя ссылка.
+func RevokePending(db *gorm.DB, userID uint, now time.Time) error {
+	return db.Model(&models.Invitation{}).
+		Where("user_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// InvitationStatus возвращает состояние приглашения: pending, accepted, revoked или expired.
+func InvitationStatus(invitation models.Invitation, now time.Time) string {
+	switch {
+	case invitation.AcceptedAt != nil:
+		return "accepted"
+	case invitation.RevokedAt != nil:
+		return "revoked"
+	case now.After(invitation.ExpiresAt):
+		return "expired"
+	}
+	return "pending"
+}
@@ -0,0 +1,109 @@
+package users
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"systemacontrolya/internal/members"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MinPasswordLength — минимальная длина пароля при создании пользователя и принятии приглашения.
+	MinPasswordLength = 10
+	assigneeRole      = "Исполнитель"
+	managerRole       = "Менеджер"
+)
+
+// passwordAlphabet не содержит похожих символов (0/O, 1/l/I), чтобы пароль было легко переписать.
+const passwordAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Snapshot — поля пользователя для журнала аудита. Пароль в журнал не попадает.
+func Snapshot(user models.User) map[string]any {
+	return map[string]any{
+		"email":           user.Email,
+		"first_name":      user.FirstName,
+		"last_name":       user.LastName,
+		"middle_name":     user.MiddleName,
+		"role_id":         user.RoleID,
+		"status":          user.Status,
+		"organization_id": user.OrganizationID,
+	}
+}
+
+func GeneratePassword() (string, error) {
+	password := make([]byte, 14)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// UnusablePassword возвращает хеш случайного пароля для пользователей, которые ещё не приняли приглашение.
+func UnusablePassword() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return utils.HashPassword(hex.EncodeToString(secret))
+}
+
+// EmailTaken проверяет без учёта регистра, занят ли email другим пользователем.
+func EmailTaken(db *gorm.DB, email string, exceptID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.User{}).Where("LOWER(email) = LOWER(?) AND id <> ?", email, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// OpenAssignments возвращает число незакрытых дефектов, назначенных пользователю.
+func OpenAssignments(db *gorm.DB, userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&models.Defect{}).
+		Where("assignee_id = ? AND status <> ?", userID, "closed").
+		Count(&count).Error
+	return count, err
+}
+
+// CheckRoleChange проверяет, что смена роли не оставит проекты без менеджера, а дефекты — без исполнителя.
+func CheckRoleChange(db *gorm.DB, user models.User, role models.Role) error {
+	if user.Role.Name == managerRole && role.Name != managerRole {
+		count, err := members.SoleManagerProjects(db, user.ID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("пользователь — единственный менеджер в проектах (%d), сначала добавьте им другого менеджера", count)
+		}
+	}
+	if user.Role.Name == assigneeRole && role.Name != assigneeRole {
+		count, err := OpenAssignments(db, user.ID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("на пользователе открытые дефекты (%d), сначала переназначьте их", count)
+		}
+	}
+	return nil
+}
+
+// CheckDeactivate проверяет, что пользователя можно деактивировать: проект не может остаться без менеджера.
+func CheckDeactivate(db *gorm.DB, user models.User) error {
+	count, err := members.SoleManagerProjects(db, user.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("пользователь — единственный менеджер в проектах (%d), сначала добавьте им другого менеджера", count)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// AppURL возвращает адрес клиентского приложения для ссылок в QR-кодах, письмах и выгрузках.
+func AppURL() string {
+	url := os.Getenv("APP_URL")
+	if url == "" {
+		url = "http://localhost:3000"
+	}
+	return strings.TrimRight(url, "/")
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseIDs разбирает список ID из параметра запроса вида "1,2,3".
+func ParseIDs(raw string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("неверный ID %q", part)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// ParseOptionalID разбирает необязательный ID; пустая строка даёт nil.
+func ParseOptionalID(raw string) (*uint, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("неверный ID %q", raw)
+	}
+	value := uint(id)
+	return &value, nil
+}
@@ -0,0 +1,74 @@
+package xlsxexport
+
+import (
+	"fmt"
+	"io"
+
+	"systemacontrolya/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const costSheet = "Затраты"
+
+// WriteCostReport пишет свод затрат: строка на группу и итог. title — название разреза для первой колонки.
+func WriteCostReport(title string, rows []models.CostReportRow, w io.Writer) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	header, err := file.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"E5E7EB"}},
+	})
+	if err != nil {
+		return err
+	}
+	moneyFormat := "#,##0.00"
+	money, err := file.NewStyle(&excelize.Style{CustomNumFmt: &moneyFormat})
+	if err != nil {
+		return err
+	}
+	if err := file.SetSheetName("Sheet1", costSheet); err != nil {
+		return err
+	}
+
+	titles := []string{title, "Дефектов", "Работы (оценка)", "Работы (факт)", "Материалы", "Итого"}
+	widths := []float64{35, 10, 17, 17, 17, 17}
+	for i, width := range widths {
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		if err := file.SetColWidth(costSheet, col, col, width); err != nil {
+			return err
+		}
+	}
+	if err := file.SetColStyle(costSheet, "C:F", money); err != nil {
+		return err
+	}
+	if err := file.SetSheetRow(costSheet, "A1", &titles); err != nil {
+		return err
+	}
+	if err := file.SetCellStyle(costSheet, "A1", "F1", header); err != nil {
+		return err
+	}
+
+	var total models.CostReportRow
+	for i, row := range rows {
+		values := []any{row.Name, row.Defects, row.EstimatedLabour, row.ActualLabour, row.Materials, row.Total}
+		if err := file.SetSheetRow(costSheet, fmt.Sprintf("A%d", i+2), &values); err != nil {
+			return err
+		}
+		total.EstimatedLabour += row.EstimatedLabour
+		total.ActualLabour += row.ActualLabour
+		total.Materials += row.Materials
+		total.Total += row.Total
+	}
+	last := len(rows) + 2
+	totals := []any{"Итого", nil, total.EstimatedLabour, total.ActualLabour, total.Materials, total.Total}
+	if err := file.SetSheetRow(costSheet, fmt.Sprintf("A%d", last), &totals); err != nil {
+		return err
+	}
+	if err := file.SetCellStyle(costSheet, fmt.Sprintf("A%d", last), fmt.Sprintf("B%d", last), header); err != nil {
+		return err
+	}
+
+	return file.Write(w)
+}
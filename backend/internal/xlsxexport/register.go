@@ -0,0 +1,306 @@
+package xlsxexport
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"systemacontrolya/internal/costs"
+	"systemacontrolya/internal/customfields"
+	"systemacontrolya/internal/models"
+	"systemacontrolya/internal/utils"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const summarySheet = "Сводка"
+
+var statuses = []string{"new", "in_progress", "resolved", "closed", "reopened"}
+
+var columns = []struct {
+	title string
+	width float64
+}{
+	{"№", 8},
+	{"Название", 40},
+	{"Описание", 60},
+	{"Проект", 25},
+	{"Место", 25},
+	{"Приоритет", 14},
+	{"Статус", 14},
+	{"Автор", 25},
+	{"Исполнитель", 25},
+	{"Организация", 25},
+	{"Метки", 25},
+	{"Создан", 17},
+	{"Срок", 17},
+	{"Закрыт", 17},
+	{"Ответственный", 16},
+	{"Работы (оценка)", 15},
+	{"Работы (факт)", 15},
+	{"Материалы", 15},
+	{"Ссылка", 12},
+}
+
+// Register — реестр дефектов в XLSX. Листы пишутся потоково, поэтому в памяти держится только текущая строка.
+type Register struct {
+	file       *excelize.File
+	header     int
+	date       int
+	money      int
+	link       int
+	sheetNames map[string]bool
+	groups     []string
+	counts     map[string]map[string]int
+	overdue    map[string]int
+	now        time.Time
+}
+
+type Sheet struct {
+	register *Register
+	writer   *excelize.StreamWriter
+	group    string
+	fields   []models.CustomField
+	row      int
+}
+
+func NewRegister() (*Register, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName("Sheet1", summarySheet); err != nil {
+		return nil, err
+	}
+
+	header, err := file.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"E5E7EB"}},
+		Alignment: &excelize.Alignment{Vertical: "center", WrapText: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	dateFormat := "dd.mm.yyyy hh:mm"
+	date, err := file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return nil, err
+	}
+	moneyFormat := "#,##0.00"
+	money, err := file.NewStyle(&excelize.Style{CustomNumFmt: &moneyFormat})
+	if err != nil {
+		return nil, err
+	}
+	link, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Color: "1D4ED8", Underline: "single"}})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Register{
+		file:       file,
+		header:     header,
+		date:       date,
+		money:      money,
+		link:       link,
+		sheetNames: map[string]bool{strings.ToLower(summarySheet): true},
+		counts:     map[string]map[string]int{},
+		overdue:    map[string]int{},
+		now:        time.Now(),
+	}, nil
+}
+
+// StartSheet создаёт лист группы; fields добавляет колонки пользовательских полей проекта.
+func (r *Register) StartSheet(group string, fields []models.CustomField) (*Sheet, error) {
+	name := r.uniqueSheetName(group)
+	if _, err := r.file.NewSheet(name); err != nil {
+		return nil, err
+	}
+
+	writer, err := r.file.NewStreamWriter(name)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]any, 0, len(columns)+len(fields))
+	for i, col := range columns {
+		if err := writer.SetColWidth(i+1, i+1, col.width); err != nil {
+			return nil, err
+		}
+		header = append(header, excelize.Cell{Value: col.title, StyleID: r.header})
+	}
+	for i, field := range fields {
+		if err := writer.SetColWidth(len(columns)+i+1, len(columns)+i+1, 20); err != nil {
+			return nil, err
+		}
+		header = append(header, excelize.Cell{Value: field.Name, StyleID: r.header})
+	}
+	if err := writer.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return nil, err
+	}
+	if err := writer.SetRow("A1", header); err != nil {
+		return nil, err
+	}
+
+	r.groups = append(r.groups, group)
+	r.counts[group] = map[string]int{}
+
+	return &Sheet{register: r, writer: writer, group: group, fields: fields, row: 1}, nil
+}
+
+// Add дописывает строку дефекта; cost — затраты на дефект из costs.ForDefects.
+func (s *Sheet) Add(defect models.Defect, cost costs.DefectCosts) error {
+	r := s.register
+
+	labels := make([]string, 0, len(defect.Labels))
+	for _, label := range defect.Labels {
+		labels = append(labels, label.Name)
+	}
+	location := ""
+	if defect.Location != nil {
+		location = defect.Location.Name
+	}
+	organization := ""
+	if defect.Organization != nil {
+		organization = defect.Organization.Name
+	}
+	party := ""
+	if defect.ResponsibleParty != nil {
+		party = models.ResponsiblePartyNames[*defect.ResponsibleParty]
+	}
+	link := fmt.Sprintf("%s/defects/%d", utils.AppURL(), defect.ID)
+
+	values := []any{
+		defect.ID,
+		defect.Title,
+		defect.Description,
+		defect.Project.Name,
+		location,
+		models.PriorityNames[defect.Priority],
+		models.StatusNames[defect.Status],
+		defect.Author.FullName(),
+		defect.Assignee.FullName(),
+		organization,
+		strings.Join(labels, ", "),
+		excelize.Cell{Value: defect.CreatedAt, StyleID: r.date},
+		r.dateCell(defect.DueDate),
+		r.dateCell(defect.ClosedAt),
+		party,
+		excelize.Cell{Value: cost.EstimatedLabour, StyleID: r.money},
+		excelize.Cell{Value: cost.ActualLabour, StyleID: r.money},
+		excelize.Cell{Value: cost.Materials, StyleID: r.money},
+		excelize.Cell{Formula: fmt.Sprintf(`HYPERLINK("%s","Открыть")`, link), Value: "Открыть", StyleID: r.link},
+	}
+	for _, field := range s.fields {
+		values = append(values, customfields.Display(defect.CustomFields[field.Key]))
+	}
+
+	s.row++
+	cell, err := excelize.CoordinatesToCellName(1, s.row)
+	if err != nil {
+		return err
+	}
+	if err := s.writer.SetRow(cell, values); err != nil {
+		return err
+	}
+
+	r.counts[s.group][defect.Status]++
+	if defect.Status != "closed" && defect.DueDate != nil && defect.DueDate.Before(r.now) {
+		r.overdue[s.group]++
+	}
+	return nil
+}
+
+func (s *Sheet) Close() error {
+	return s.writer.Flush()
+}
+
+// Write дописывает лист сводки и отдаёт книгу; временные файлы потоковой записи удаляются.
+func (r *Register) Write(w io.Writer) error {
+	defer r.file.Close()
+
+	writer, err := r.file.NewStreamWriter(summarySheet)
+	if err != nil {
+		return err
+	}
+	if err := writer.SetColWidth(1, 1, 35); err != nil {
+		return err
+	}
+	if err := writer.SetColWidth(2, len(statuses)+3, 13); err != nil {
+		return err
+	}
+
+	header := []any{excelize.Cell{Value: "Группа", StyleID: r.header}, excelize.Cell{Value: "Всего", StyleID: r.header}}
+	for _, status := range statuses {
+		header = append(header, excelize.Cell{Value: models.StatusNames[status], StyleID: r.header})
+	}
+	header = append(header, excelize.Cell{Value: "Просрочено", StyleID: r.header})
+	if err := writer.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	totals := make([]int, len(statuses)+2)
+	for i, group := range r.groups {
+		row := []any{group}
+		sum := 0
+		for _, status := range statuses {
+			sum += r.counts[group][status]
+		}
+		row = append(row, sum)
+		totals[0] += sum
+		for j, status := range statuses {
+			row = append(row, r.counts[group][status])
+			totals[j+1] += r.counts[group][status]
+		}
+		row = append(row, r.overdue[group])
+		totals[len(totals)-1] += r.overdue[group]
+
+		if err := writer.SetRow(fmt.Sprintf("A%d", i+2), row); err != nil {
+			return err
+		}
+	}
+
+	totalRow := []any{excelize.Cell{Value: "Итого", StyleID: r.header}}
+	for _, total := range totals {
+		totalRow = append(totalRow, excelize.Cell{Value: total, StyleID: r.header})
+	}
+	if err := writer.SetRow(fmt.Sprintf("A%d", len(r.groups)+2), totalRow); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	r.file.SetActiveSheet(0)
+	return r.file.Write(w)
+}
+
+func (r *Register) dateCell(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return excelize.Cell{Value: *t, StyleID: r.date}
+}
+
+// uniqueSheetName приводит название к ограничениям Excel: до 31 символа, без []:*?/\ и без повторов.
+func (r *Register) uniqueSheetName(title string) string {
+	replacer := strings.NewReplacer("[", "(", "]", ")", ":", " ", "*", " ", "?", " ", "/", "-", "\\", "-")
+	base := strings.TrimSpace(replacer.Replace(title))
+	if base == "" {
+		base = "Лист"
+	}
+	base = truncate(base, 31)
+
+	name := base
+	for i := 2; r.sheetNames[strings.ToLower(name)]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		name = truncate(base, 31-len([]rune(suffix))) + suffix
+	}
+	r.sheetNames[strings.ToLower(name)] = true
+	return name
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
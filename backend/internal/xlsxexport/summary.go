@@ -0,0 +1,149 @@
+package xlsxexport
+
+import (
+	"fmt"
+	"io"
+
+	"systemacontrolya/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// WritePeriodSummary пишет сводку руководителя в книгу: показатели на первом листе,
+// новые критические дефекты, просроченные дефекты и принятые отчёты — на отдельных.
+func WritePeriodSummary(summary models.PeriodSummary, w io.Writer) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	header, err := file.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"E5E7EB"}},
+	})
+	if err != nil {
+		return err
+	}
+	dateFormat := "dd.mm.yyyy"
+	date, err := file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+	if err := file.SetSheetName("Sheet1", summarySheet); err != nil {
+		return err
+	}
+	metrics := [][]any{
+		{"Сводка", summary.Title},
+		{"Период", fmt.Sprintf("%s — %s", summary.From.Format("02.01.2006 15:04"), summary.To.Format("02.01.2006 15:04"))},
+		{"Всего дефектов", summary.Total},
+	}
+	for _, status := range statuses {
+		metrics = append(metrics, []any{models.StatusNames[status], summary.ByStatus[status]})
+	}
+	metrics = append(metrics,
+		[]any{"Выявлено за период", summary.Created},
+		[]any{"Закрыто за период", summary.Closed},
+		[]any{"Просрочено сейчас", len(summary.Overdue)},
+		[]any{"Принято отчётов", len(summary.Approved)},
+	)
+	for i, row := range metrics {
+		if err := file.SetSheetRow(summarySheet, fmt.Sprintf("A%d", i+1), &row); err != nil {
+			return err
+		}
+	}
+	if err := file.SetColWidth(summarySheet, "A", "A", 25); err != nil {
+		return err
+	}
+	if err := file.SetColWidth(summarySheet, "B", "B", 45); err != nil {
+		return err
+	}
+	if err := file.SetCellStyle(summarySheet, "A1", fmt.Sprintf("A%d", len(metrics)), header); err != nil {
+		return err
+	}
+
+	defectHeader := []string{"№", "Дефект", "Проект", "Место", "Приоритет", "Статус", "Исполнитель", "Выявлен", "Срок"}
+	defectWidths := []float64{8, 45, 25, 25, 14, 14, 25, 12, 12}
+	defectRow := func(defect models.Defect) []any {
+		location := ""
+		if defect.Location != nil {
+			location = defect.Location.Name
+		}
+		var dueDate any
+		if defect.DueDate != nil {
+			dueDate = *defect.DueDate
+		}
+		return []any{
+			defect.ID,
+			defect.Title,
+			defect.Project.Name,
+			location,
+			models.PriorityNames[defect.Priority],
+			models.StatusNames[defect.Status],
+			defect.Assignee.FullName(),
+			defect.CreatedAt,
+			dueDate,
+		}
+	}
+
+	sheets := []struct {
+		name   string
+		header []string
+		widths []float64
+		dates  string // колонки с датами
+		rows   [][]any
+	}{
+		{name: "Критические", header: defectHeader, widths: defectWidths, dates: "H:I"},
+		{name: "Просроченные", header: defectHeader, widths: defectWidths, dates: "H:I"},
+		{
+			name:   "Принятые отчёты",
+			header: []string{"№", "Отчёт", "Дефект", "Проект", "Исполнитель", "Принят"},
+			widths: []float64{8, 40, 45, 25, 25, 12},
+			dates:  "F:F",
+		},
+	}
+	for _, defect := range summary.NewCritical {
+		sheets[0].rows = append(sheets[0].rows, defectRow(defect))
+	}
+	for _, defect := range summary.Overdue {
+		sheets[1].rows = append(sheets[1].rows, defectRow(defect))
+	}
+	for _, review := range summary.Approved {
+		report := review.Report
+		sheets[2].rows = append(sheets[2].rows, []any{
+			report.ID,
+			report.Title,
+			fmt.Sprintf("№ %d. %s", report.Defect.ID, report.Defect.Title),
+			report.Project.Name,
+			report.User.FullName(),
+			review.CreatedAt,
+		})
+	}
+
+	for _, sheet := range sheets {
+		if _, err := file.NewSheet(sheet.name); err != nil {
+			return err
+		}
+		for i, width := range sheet.widths {
+			col, _ := excelize.ColumnNumberToName(i + 1)
+			if err := file.SetColWidth(sheet.name, col, col, width); err != nil {
+				return err
+			}
+		}
+		if err := file.SetColStyle(sheet.name, sheet.dates, date); err != nil {
+			return err
+		}
+		if err := file.SetSheetRow(sheet.name, "A1", &sheet.header); err != nil {
+			return err
+		}
+		last, _ := excelize.ColumnNumberToName(len(sheet.header))
+		if err := file.SetCellStyle(sheet.name, "A1", last+"1", header); err != nil {
+			return err
+		}
+		for i, row := range sheet.rows {
+			if err := file.SetSheetRow(sheet.name, fmt.Sprintf("A%d", i+2), &row); err != nil {
+				return err
+			}
+		}
+	}
+
+	file.SetActiveSheet(0)
+	return file.Write(w)
+}
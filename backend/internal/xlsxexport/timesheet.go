@@ -0,0 +1,79 @@
+package xlsxexport
+
+import (
+	"fmt"
+	"io"
+
+	"systemacontrolya/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const timeSheet = "Трудозатраты"
+
+// WriteTimeReport пишет отчёт по трудозатратам: строка на период, проект и исполнителя и итог по часам.
+func WriteTimeReport(rows []models.TimeReportRow, w io.Writer) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	header, err := file.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"E5E7EB"}},
+	})
+	if err != nil {
+		return err
+	}
+	dateFormat := "dd.mm.yyyy"
+	date, err := file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+	hoursFormat := "0.00"
+	hours, err := file.NewStyle(&excelize.Style{CustomNumFmt: &hoursFormat})
+	if err != nil {
+		return err
+	}
+	if err := file.SetSheetName("Sheet1", timeSheet); err != nil {
+		return err
+	}
+
+	titles := []string{"Период", "Проект", "Исполнитель", "Часы", "Записей", "Дефектов"}
+	widths := []float64{12, 30, 30, 10, 10, 10}
+	for i, width := range widths {
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		if err := file.SetColWidth(timeSheet, col, col, width); err != nil {
+			return err
+		}
+	}
+	if err := file.SetColStyle(timeSheet, "A", date); err != nil {
+		return err
+	}
+	if err := file.SetColStyle(timeSheet, "D", hours); err != nil {
+		return err
+	}
+	if err := file.SetSheetRow(timeSheet, "A1", &titles); err != nil {
+		return err
+	}
+	if err := file.SetCellStyle(timeSheet, "A1", "F1", header); err != nil {
+		return err
+	}
+
+	var total float64
+	for i, row := range rows {
+		values := []any{row.Period, row.ProjectName, row.UserName, row.Hours, row.Entries, row.Defects}
+		if err := file.SetSheetRow(timeSheet, fmt.Sprintf("A%d", i+2), &values); err != nil {
+			return err
+		}
+		total += row.Hours
+	}
+	last := len(rows) + 2
+	totals := []any{"Итого", nil, nil, total}
+	if err := file.SetSheetRow(timeSheet, fmt.Sprintf("A%d", last), &totals); err != nil {
+		return err
+	}
+	if err := file.SetCellStyle(timeSheet, fmt.Sprintf("A%d", last), fmt.Sprintf("C%d", last), header); err != nil {
+		return err
+	}
+
+	return file.Write(w)
+}
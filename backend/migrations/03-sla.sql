@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS sla_policies (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    priority VARCHAR(20) NOT NULL CHECK (priority IN ('low', 'medium', 'high', 'critical')),
+    response_hours INTEGER NOT NULL,
+    resolution_hours INTEGER NOT NULL,
+    extension_hours INTEGER NOT NULL DEFAULT 72,
+    CONSTRAINT idx_sla_project_priority UNIQUE (project_id, priority)
+);
+
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS response_due_date TIMESTAMP WITH TIME ZONE;
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_defects_due_date ON defects(due_date);
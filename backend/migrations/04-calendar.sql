@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS work_calendars (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    timezone VARCHAR(50) NOT NULL DEFAULT 'Europe/Moscow',
+    work_start VARCHAR(5) NOT NULL DEFAULT '09:00',
+    work_end VARCHAR(5) NOT NULL DEFAULT '18:00',
+    weekends JSONB NOT NULL DEFAULT '[0, 6]'::jsonb,
+    project_id INTEGER UNIQUE REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS holidays (
+    id SERIAL PRIMARY KEY,
+    calendar_id INTEGER NOT NULL REFERENCES work_calendars(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    working BOOLEAN NOT NULL DEFAULT FALSE,
+    recurring BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_holidays_calendar_id ON holidays(calendar_id);
+
+-- Общий календарь: пятидневка и нерабочие праздничные дни по ст. 112 ТК РФ.
+-- Переносы выходных утверждаются ежегодно и добавляются администратором отдельными датами.
+INSERT INTO work_calendars (name, timezone, work_start, work_end, weekends)
+SELECT 'Производственный календарь РФ', 'Europe/Moscow', '09:00', '18:00', '[0, 6]'::jsonb
+WHERE NOT EXISTS (SELECT 1 FROM work_calendars WHERE project_id IS NULL);
+
+INSERT INTO holidays (calendar_id, date, name, recurring)
+SELECT wc.id, h.date::date, h.name, TRUE
+FROM (SELECT id FROM work_calendars WHERE project_id IS NULL ORDER BY id LIMIT 1) wc
+CROSS JOIN (VALUES
+    ('2000-01-01', 'Новогодние каникулы'),
+    ('2000-01-02', 'Новогодние каникулы'),
+    ('2000-01-03', 'Новогодние каникулы'),
+    ('2000-01-04', 'Новогодние каникулы'),
+    ('2000-01-05', 'Новогодние каникулы'),
+    ('2000-01-06', 'Новогодние каникулы'),
+    ('2000-01-07', 'Рождество Христово'),
+    ('2000-01-08', 'Новогодние каникулы'),
+    ('2000-02-23', 'День защитника Отечества'),
+    ('2000-03-08', 'Международный женский день'),
+    ('2000-05-01', 'Праздник Весны и Труда'),
+    ('2000-05-09', 'День Победы'),
+    ('2000-06-12', 'День России'),
+    ('2000-11-04', 'День народного единства')
+) AS h(date, name)
+WHERE NOT EXISTS (SELECT 1 FROM holidays WHERE calendar_id = wc.id);
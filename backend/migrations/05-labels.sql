@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS labels (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '#9ca3af',
+    kind VARCHAR(20) NOT NULL DEFAULT 'other' CHECK (kind IN ('trade', 'cause', 'other')),
+    CONSTRAINT idx_labels_project_name UNIQUE (project_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS defect_labels (
+    defect_id INTEGER NOT NULL REFERENCES defects(id) ON DELETE CASCADE,
+    label_id INTEGER NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+    PRIMARY KEY (defect_id, label_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_defect_labels_label_id ON defect_labels(label_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS locations (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES locations(id) ON DELETE RESTRICT,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('site', 'building', 'floor', 'room')),
+    path VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_locations_project_id ON locations(project_id);
+CREATE INDEX IF NOT EXISTS idx_locations_parent_id ON locations(parent_id);
+CREATE INDEX IF NOT EXISTS idx_locations_path ON locations(path varchar_pattern_ops);
+
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS location_id INTEGER REFERENCES locations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_defects_location_id ON defects(location_id);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS floor_plans (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    location_id INTEGER REFERENCES locations(id) ON DELETE SET NULL,
+    uploaded_by_id INTEGER REFERENCES users(id),
+    name VARCHAR(100) NOT NULL,
+    file_path VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(50) NOT NULL,
+    preview_path VARCHAR(255),
+    width INTEGER,
+    height INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_floor_plans_project_id ON floor_plans(project_id);
+CREATE INDEX IF NOT EXISTS idx_floor_plans_location_id ON floor_plans(location_id);
+
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS plan_id INTEGER REFERENCES floor_plans(id) ON DELETE SET NULL;
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS pin_x DOUBLE PRECISION CHECK (pin_x BETWEEN 0 AND 1);
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS pin_y DOUBLE PRECISION CHECK (pin_y BETWEEN 0 AND 1);
+
+CREATE INDEX IF NOT EXISTS idx_defects_plan_id ON defects(plan_id);
@@ -0,0 +1,3 @@
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS public_code VARCHAR(16);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_defects_public_code ON defects(public_code);
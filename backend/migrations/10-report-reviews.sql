@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS report_reviews (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    reviewer_id INTEGER NOT NULL REFERENCES users(id),
+    stage VARCHAR(20) NOT NULL CHECK (stage IN ('engineer', 'manager')),
+    decision VARCHAR(20) NOT NULL CHECK (decision IN ('approve', 'reject')),
+    comment TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_reviews_report_id ON report_reviews(report_id);
+CREATE INDEX IF NOT EXISTS idx_defects_closed_at ON defects(closed_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id SERIAL PRIMARY KEY,
+    filename VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'applied' CHECK (status IN ('applied', 'rolled_back')),
+    total INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    rolled_back_at TIMESTAMP WITH TIME ZONE,
+    user_id INTEGER NOT NULL REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user_id ON import_jobs(user_id);
+
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS import_job_id INTEGER REFERENCES import_jobs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_defects_import_job_id ON defects(import_job_id);
@@ -0,0 +1,30 @@
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_manager_id_key;
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS uni_projects_manager_id;
+
+CREATE TABLE IF NOT EXISTS project_members (
+    id SERIAL PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('manager', 'engineer', 'assignee', 'observer')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_project_members_project_user ON project_members(project_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_project_members_user_id ON project_members(user_id);
+
+-- Существующие проекты: менеджер, авторы дефектов и исполнители становятся участниками.
+INSERT INTO project_members (project_id, user_id, role)
+SELECT id, manager_id, 'manager' FROM projects
+ON CONFLICT (project_id, user_id) DO NOTHING;
+
+INSERT INTO project_members (project_id, user_id, role)
+SELECT DISTINCT d.project_id, d.author_id, 'engineer'
+FROM defects d JOIN users u ON u.id = d.author_id JOIN roles r ON r.id = u.role_id
+WHERE r.name = 'Инженер'
+ON CONFLICT (project_id, user_id) DO NOTHING;
+
+INSERT INTO project_members (project_id, user_id, role)
+SELECT DISTINCT d.project_id, d.assignee_id, 'assignee'
+FROM defects d JOIN users u ON u.id = d.assignee_id JOIN roles r ON r.id = u.role_id
+WHERE r.name = 'Исполнитель'
+ON CONFLICT (project_id, user_id) DO NOTHING;
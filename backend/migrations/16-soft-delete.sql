@@ -0,0 +1,16 @@
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_defects_deleted_at ON defects(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_reports_deleted_at ON reports(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_projects_deleted_at ON projects(deleted_at);
+
+-- Записи в корзине не занимают название проекта и email: уникальность проверяется среди неудалённых.
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_name_active ON projects(name) WHERE deleted_at IS NULL;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_active ON users(email) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- Индексы для аналитики руководителя: выборки по периодам событий и открытым дефектам.
+CREATE INDEX IF NOT EXISTS idx_defects_assigned_at ON defects(assigned_at) WHERE deleted_at IS NULL AND assigned_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_defects_resolved_at ON defects(resolved_at) WHERE deleted_at IS NULL AND resolved_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_defects_open ON defects(project_id, priority, created_at) WHERE deleted_at IS NULL AND status <> 'closed';
@@ -0,0 +1,49 @@
+-- Хранилище статистики для дашбордов. Таблицы пересчитываются пакетом stats по проектам,
+-- в которых что-то изменилось с прошлого обновления.
+
+-- Снимок дефектов на конец дня: сколько дефектов проекта было в каждом статусе и приоритете.
+CREATE TABLE IF NOT EXISTS defect_stats_daily (
+    day DATE NOT NULL,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    priority VARCHAR(20) NOT NULL,
+    total INTEGER NOT NULL,
+    overdue INTEGER NOT NULL,
+    PRIMARY KEY (day, project_id, status, priority)
+);
+
+CREATE TABLE IF NOT EXISTS defect_label_stats (
+    label_id INTEGER PRIMARY KEY REFERENCES labels(id) ON DELETE CASCADE,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    total INTEGER NOT NULL,
+    open INTEGER NOT NULL,
+    closed INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS project_stats (
+    project_id INTEGER PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    defects INTEGER NOT NULL,
+    authors INTEGER NOT NULL,
+    assignees INTEGER NOT NULL
+);
+
+-- Отчёты, поступившие за день.
+CREATE TABLE IF NOT EXISTS report_stats_daily (
+    day DATE NOT NULL,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    total INTEGER NOT NULL,
+    PRIMARY KEY (day, project_id)
+);
+
+-- Проекты, изменения в которых не видны по updated_at дефектов (метки, откат импорта, корзина).
+CREATE TABLE IF NOT EXISTS stats_pending (
+    project_id INTEGER PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS stats_refreshes (
+    name VARCHAR(50) PRIMARY KEY,
+    refreshed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_created_at ON reports(created_at);
+CREATE INDEX IF NOT EXISTS idx_defect_label_stats_project ON defect_label_stats(project_id);
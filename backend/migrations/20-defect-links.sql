@@ -0,0 +1,18 @@
+-- Связи между дефектами: source_id <тип> target_id. duplicate_of — source дубликат target,
+-- blocks — source блокирует target, parent — target родительский дефект source.
+CREATE TABLE IF NOT EXISTS defect_links (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('duplicate_of', 'blocks', 'relates_to', 'parent')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    source_id INTEGER NOT NULL REFERENCES defects(id) ON DELETE CASCADE,
+    target_id INTEGER NOT NULL REFERENCES defects(id) ON DELETE CASCADE,
+    created_by_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    CHECK (source_id <> target_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_defect_links_unique ON defect_links(source_id, target_id, type);
+CREATE INDEX IF NOT EXISTS idx_defect_links_target ON defect_links(target_id, type);
+
+-- У дефекта не больше одного оригинала и одного родителя.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_defect_links_single_duplicate ON defect_links(source_id) WHERE type = 'duplicate_of';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_defect_links_single_parent ON defect_links(source_id) WHERE type = 'parent';
@@ -0,0 +1,15 @@
+-- Поиск похожих дефектов: триграммы по названию и описанию и перцептивные хеши фотографий.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_defects_title_trgm ON defects USING gin (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_defects_description_trgm ON defects USING gin (description gin_trgm_ops);
+
+-- dHash вложения-изображения, 64 бита.
+CREATE TABLE IF NOT EXISTS defect_photo_hashes (
+    id SERIAL PRIMARY KEY,
+    path VARCHAR(255) NOT NULL,
+    hash BIGINT NOT NULL,
+    defect_id INTEGER NOT NULL REFERENCES defects(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_defect_photo_hashes_defect_path ON defect_photo_hashes(defect_id, path);
@@ -0,0 +1,16 @@
+-- Учёт трудозатрат: записи исполнителей о выполненной работе и оценка менеджера по дефекту.
+CREATE TABLE IF NOT EXISTS work_logs (
+    id SERIAL PRIMARY KEY,
+    work_date DATE NOT NULL,
+    hours NUMERIC(5, 2) NOT NULL CHECK (hours > 0 AND hours <= 24),
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    defect_id INTEGER NOT NULL REFERENCES defects(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_work_logs_defect_id ON work_logs(defect_id);
+CREATE INDEX IF NOT EXISTS idx_work_logs_user_date ON work_logs(user_id, work_date);
+
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS estimate_hours NUMERIC(6, 2) CHECK (estimate_hours > 0);
@@ -0,0 +1,33 @@
+-- Стоимость устранения дефектов: оценка и факт работ, ответственная сторона, справочник материалов
+-- проекта и расход материалов по дефектам. Цена материала фиксируется в момент списания.
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS estimated_labour_cost NUMERIC(12, 2) CHECK (estimated_labour_cost >= 0);
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS actual_labour_cost NUMERIC(12, 2) CHECK (actual_labour_cost >= 0);
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS responsible_party VARCHAR(30)
+    CHECK (responsible_party IN ('general_contractor', 'subcontractor', 'warranty'));
+
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS labour_cost NUMERIC(12, 2) CHECK (labour_cost >= 0);
+
+CREATE TABLE IF NOT EXISTS materials (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    unit VARCHAR(20) NOT NULL,
+    unit_price NUMERIC(12, 2) NOT NULL CHECK (unit_price >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_materials_project_name ON materials(project_id, name);
+
+CREATE TABLE IF NOT EXISTS defect_materials (
+    id SERIAL PRIMARY KEY,
+    quantity NUMERIC(12, 3) NOT NULL CHECK (quantity > 0),
+    unit_price NUMERIC(12, 2) NOT NULL CHECK (unit_price >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    defect_id INTEGER NOT NULL REFERENCES defects(id) ON DELETE CASCADE,
+    material_id INTEGER NOT NULL REFERENCES materials(id) ON DELETE RESTRICT,
+    report_id INTEGER REFERENCES reports(id) ON DELETE SET NULL,
+    created_by_id INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_defect_materials_defect_id ON defect_materials(defect_id);
+CREATE INDEX IF NOT EXISTS idx_defect_materials_material_id ON defect_materials(material_id);
@@ -0,0 +1,27 @@
+-- Организации: генподрядчик и субподрядчики. Пользователи состоят в организации, проекты перечисляют
+-- привлечённые организации, дефект сначала назначается организации, затем её сотруднику.
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(150) NOT NULL,
+    kind VARCHAR(20) NOT NULL DEFAULT 'subcontractor' CHECK (kind IN ('general_contractor', 'subcontractor')),
+    tax_id VARCHAR(12),
+    contact VARCHAR(200),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_name ON organizations(LOWER(name));
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_users_organization_id ON users(organization_id);
+
+CREATE TABLE IF NOT EXISTS project_organizations (
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (project_id, organization_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_organizations_organization_id ON project_organizations(organization_id);
+
+ALTER TABLE defects ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_defects_organization_id ON defects(organization_id);
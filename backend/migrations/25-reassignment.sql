@@ -0,0 +1,40 @@
+-- История назначений дефектов и замещение на время отсутствия. Пока замещение действует,
+-- новые назначения и проверки отчётов пользователя переходят к заместителю.
+CREATE TABLE IF NOT EXISTS defect_assignments (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('assign', 'reassign')),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    defect_id INTEGER NOT NULL REFERENCES defects(id) ON DELETE CASCADE,
+    from_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    to_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    delegated_from_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    from_organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+    to_organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_defect_assignments_defect_id ON defect_assignments(defect_id);
+
+-- Назначения, сделанные до появления истории.
+INSERT INTO defect_assignments (kind, created_at, defect_id, to_user_id, to_organization_id)
+SELECT 'assign', COALESCE(d.assigned_at, d.created_at), d.id, d.assignee_id, d.organization_id
+FROM defects d
+WHERE d.assignee_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM defect_assignments a WHERE a.defect_id = d.id);
+
+CREATE TABLE IF NOT EXISTS delegations (
+    id SERIAL PRIMARY KEY,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    comment VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delegate_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    CHECK (starts_on <= ends_on),
+    CHECK (user_id <> delegate_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_delegations_user_period ON delegations(user_id, starts_on, ends_on);
+CREATE INDEX IF NOT EXISTS idx_delegations_delegate_id ON delegations(delegate_id);